@@ -0,0 +1,210 @@
+// Package admin provides pages to list, show, create, edit and delete the
+// models registered on the database of an application.
+//
+// The admin is a controller added to the router like any other, middlewares
+// passed to Add protect it:
+//
+//	adm, err := admin.New(admin.Options{Prefix: "/admin"})
+//	if err != nil {
+//		return err
+//	}
+//	app.Router.Add(adm.Controller, requireAdmin)
+//
+// Every page is also available as JSON, when the request accepts
+// application/json or has the query format=json. Create and update accept
+// forms and JSON bodies.
+//
+// Requests changing data, i.e. which are not GET or HEAD, must send the CSRF
+// token of the session in the csrf_token form field or in the X-CSRF-Token
+// header, they are forbidden otherwise. The token is set in the X-CSRF-Token
+// header of every response. The admin needs a session store for the token.
+//
+// The pages are rendered with the default templates of the package. A page is
+// replaced by adding a template named admin/<page> to the views of the
+// application, where page is one of home, list, show and form. The templates
+// get the Page under the Admin key of the data, forms must have a hidden
+// csrf_token field with the value of Page.CSRFToken.
+package admin
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/pagination"
+	"github.com/gernest/utron/view"
+	"github.com/jinzhu/gorm"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Options are settings for the Admin.
+type Options struct {
+	// Prefix is the path the pages are served under. It defaults to /admin.
+	Prefix string
+
+	// PerPage is the number of items listed per page. It defaults to
+	// pagination.DefaultPerPage.
+	PerPage int
+
+	// Models are the names of the models managed by the admin, e.g. Book. All
+	// registered models are managed when empty.
+	Models []string
+}
+
+// Admin serves the admin pages.
+type Admin struct {
+	opts Options
+	view view.View
+}
+
+// New returns an Admin with the settings of opts.
+func New(opts Options) (*Admin, error) {
+	if opts.Prefix == "" {
+		opts.Prefix = "/admin"
+	}
+	opts.Prefix = "/" + strings.Trim(opts.Prefix, "/")
+	v, err := view.NewSimpleViewWithOptions("templates", view.Options{FS: templates})
+	if err != nil {
+		return nil, err
+	}
+	return &Admin{opts: opts, view: v}, nil
+}
+
+// Controller returns a new admin controller, it is passed to Router.Add.
+func (a *Admin) Controller() controller.Controller {
+	p := a.opts.Prefix
+	return &Controller{
+		admin: a,
+		Routes: []string{
+			"get;" + p + ";Home",
+			"get;" + p + "/{model};List",
+			"get;" + p + "/{model}/new;Add",
+			"post;" + p + "/{model};Create",
+			"get;" + p + "/{model}/{id};Show",
+			"get;" + p + "/{model}/{id}/edit;Edit",
+			"post,put,patch;" + p + "/{model}/{id};Update",
+			"post;" + p + "/{model}/{id}/delete;Delete",
+			"delete;" + p + "/{model}/{id};Delete",
+		},
+	}
+}
+
+// resources returns the resources managed by the admin.
+func (a *Admin) resources(db *gorm.DB, registered map[string]reflect.Type) []*Resource {
+	if len(a.opts.Models) > 0 {
+		only := make(map[string]reflect.Type)
+		for _, name := range a.opts.Models {
+			if typ, ok := registered[name]; ok {
+				only[name] = typ
+			}
+		}
+		registered = only
+	}
+	return resources(db, registered)
+}
+
+// Page is the data of the admin templates.
+type Page struct {
+	Prefix    string
+	Title     string
+	Resources []*Resource
+
+	// Resource is the model of the page, it is nil on the home page.
+	Resource *Resource
+
+	// Columns and Rows are the table of the list page.
+	Columns []*Column
+	Rows    []*Row
+	Pager   *pagination.Pager
+
+	// Filters are the values the list is filtered with, by column.
+	Filters map[string]string
+	Sort    string
+
+	// Item is the model value of the show and form pages.
+	Item *Row
+
+	// Action is the URL the form is submitted to.
+	Action string
+
+	// Errors are the errors of the form by field name, Error is an error
+	// which is not about a field.
+	Errors map[string]string
+	Error  string
+
+	// CSRFToken is the token of the session, forms changing data send it in
+	// the csrf_token field.
+	CSRFToken string
+}
+
+// URL returns the path of an admin page, e.g. URL "book" "1" "edit".
+func (p *Page) URL(elems ...string) string {
+	parts := []string{p.Prefix}
+	for _, e := range elems {
+		parts = append(parts, url.PathEscape(e))
+	}
+	return path.Join(parts...)
+}
+
+// Column is a column of the list page.
+type Column struct {
+	*Field
+
+	// SortURL sorts the list by the column, in the opposite direction when the
+	// list is already sorted by it.
+	SortURL string
+
+	// Sorted is asc or desc when the list is sorted by the column.
+	Sorted string
+}
+
+// Row is a model value, with its fields formatted in the order of the fields
+// of the resource.
+type Row struct {
+	ID     string
+	Values []string
+	Value  interface{}
+}
+
+func newRow(res *Resource, v interface{}) *Row {
+	row := &Row{ID: res.ID(v), Value: v}
+	for _, f := range res.Fields {
+		row.Values = append(row.Values, f.Format(v))
+	}
+	return row
+}
+
+// wantsJSON returns true if r asks for a JSON response, or has a JSON body.
+func wantsJSON(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json") || isJSON(r)
+}
+
+// isJSON returns true if the body of r is JSON.
+func isJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+// errorJSON is the body of JSON error responses.
+type errorJSON struct {
+	Error  string            `json:"error,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// decodeJSON decodes the JSON body of r into the model value v.
+func decodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("utron: invalid JSON %v", err)
+	}
+	return nil
+}
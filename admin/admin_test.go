@@ -0,0 +1,289 @@
+package admin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/models"
+	"github.com/gernest/utron/router"
+	"github.com/gernest/utron/view"
+	"github.com/gorilla/sessions"
+)
+
+type Book struct {
+	ID        int
+	Title     string
+	Pages     int
+	Published bool
+	CreatedAt time.Time
+}
+
+func newRouter(t *testing.T, v view.View) (*router.Router, *models.Model) {
+	dir, err := ioutil.TempDir("", "utron-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	db := models.NewModel()
+	if err = db.Register(&Book{}); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Open(config.DatabaseConfig{Database: "sqlite3", DatabaseConn: filepath.Join(dir, "admin.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.AutoMigrateAll()
+
+	adm, err := New(Options{PerPage: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := router.NewRouter(&router.Options{
+		Model:        db,
+		View:         v,
+		Config:       config.DefaultConfig(),
+		SessionStore: sessions.NewCookieStore([]byte("ePAPW9vJv7gHoftvQTyNj5VkWB52mlza")),
+	})
+	if err = r.Add(adm.Controller); err != nil {
+		t.Fatal(err)
+	}
+	return r, db
+}
+
+func do(r http.Handler, method, path string, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// session returns the session cookie and the CSRF token of a new admin
+// session.
+func session(t *testing.T, r http.Handler) (cookie, token string) {
+	w := do(r, "GET", "/admin", "")
+	token = w.Header().Get(CSRFHeader)
+	cookies := w.Result().Cookies()
+	if token == "" || len(cookies) == 0 {
+		t.Fatalf("expected a session with a CSRF token got %v", w.Header())
+	}
+	return cookies[0].Name + "=" + cookies[0].Value, token
+}
+
+func TestAdmin(t *testing.T) {
+	r, db := newRouter(t, nil)
+	cookie, token := session(t, r)
+	form := []string{"Content-Type", "application/x-www-form-urlencoded", "Cookie", cookie}
+
+	w := do(r, "GET", "/admin", "", "Cookie", cookie)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `href="/admin/book"`) {
+		t.Fatalf("home: %d %s", w.Code, w.Body)
+	}
+
+	w = do(r, "GET", "/admin/book/new", "", "Cookie", cookie)
+	if !strings.Contains(w.Body.String(), `name="title"`) || strings.Contains(w.Body.String(), `name="created_at"`) {
+		t.Errorf("expected a form with editable fields only %s", w.Body)
+	}
+	if !strings.Contains(w.Body.String(), `name="csrf_token" value="`+token+`"`) {
+		t.Errorf("expected the CSRF token in the form %s", w.Body)
+	}
+
+	for _, title := range []string{"Go", "Rust", "C"} {
+		v := url.Values{"title": {title}, "pages": {"100"}, "published": {"on"}, CSRFField: {token}}
+		w = do(r, "POST", "/admin/book", v.Encode(), form...)
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("create: %d %s", w.Code, w.Body)
+		}
+	}
+	if loc := w.Header().Get("Location"); loc != "/admin/book/3" {
+		t.Errorf("expected redirect to the book got %s", loc)
+	}
+
+	w = do(r, "POST", "/admin/book", "title=Bad&pages=many&csrf_token="+token, form...)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "not a valid number") {
+		t.Errorf("invalid: %d %s", w.Code, w.Body)
+	}
+
+	w = do(r, "GET", "/admin/book?sort=-title&per_page=2", "")
+	body := w.Body.String()
+	if strings.Index(body, "<td>Rust</td>") > strings.Index(body, "<td>Go</td>") || strings.Contains(body, "<td>C</td>") {
+		t.Errorf("expected the first page sorted by title desc %s", body)
+	}
+	if !strings.Contains(body, "page=2") {
+		t.Errorf("expected a link to the next page %s", body)
+	}
+
+	// unknown sort fields are ignored.
+	w = do(r, "GET", "/admin/book?sort=title;drop", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("sort: %d %s", w.Code, w.Body)
+	}
+
+	w = do(r, "GET", "/admin/book?title=C", "")
+	if !strings.Contains(w.Body.String(), "<td>C</td>") || strings.Contains(w.Body.String(), "<td>Go</td>") {
+		t.Errorf("expected the list filtered %s", w.Body)
+	}
+
+	w = do(r, "GET", "/admin/book/1/edit", "")
+	if !strings.Contains(w.Body.String(), `value="Go"`) || !strings.Contains(w.Body.String(), "checked") {
+		t.Errorf("edit: %s", w.Body)
+	}
+
+	w = do(r, "POST", "/admin/book/1", "title=Go+2&pages=200&csrf_token="+token, form...)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("update: %d %s", w.Code, w.Body)
+	}
+	var book Book
+	db.First(&book, 1)
+	if book.Title != "Go 2" || book.Pages != 200 || book.Published {
+		t.Errorf("expected the book to be updated got %+v", book)
+	}
+
+	w = do(r, "GET", "/admin/book/1", "")
+	if !strings.Contains(w.Body.String(), "Go 2") {
+		t.Errorf("show: %s", w.Body)
+	}
+
+	w = do(r, "GET", "/admin/book/1", "", "Cookie", cookie)
+	if !strings.Contains(w.Body.String(), `name="csrf_token" value="`+token+`"`) {
+		t.Errorf("expected the CSRF token in the delete form %s", w.Body)
+	}
+	w = do(r, "POST", "/admin/book/1/delete", "csrf_token="+token, form...)
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("delete: %d", w.Code)
+	}
+	for _, path := range []string{"/admin/book/1", "/admin/author", "/admin/author/1"} {
+		if w = do(r, "GET", path, ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected %d got %d", path, http.StatusNotFound, w.Code)
+		}
+	}
+}
+
+func TestAdminJSON(t *testing.T) {
+	r, _ := newRouter(t, nil)
+	accept := "Accept"
+	jsonType := "application/json"
+	cookie, token := session(t, r)
+	send := []string{"Content-Type", jsonType, "Cookie", cookie, CSRFHeader, token}
+
+	w := do(r, "POST", "/admin/book", `{"ID": 9, "Title": "Go", "Pages": 10}`, send...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	var book Book
+	_ = json.Unmarshal(w.Body.Bytes(), &book)
+	if book.ID != 1 || book.Title != "Go" {
+		t.Errorf("expected the ID to be ignored got %+v", book)
+	}
+
+	w = do(r, "PATCH", "/admin/book/1", `{"Pages": 20}`, send...)
+	_ = json.Unmarshal(w.Body.Bytes(), &book)
+	if w.Code != http.StatusOK || book.Title != "Go" || book.Pages != 20 {
+		t.Errorf("update: %d %s", w.Code, w.Body)
+	}
+
+	w = do(r, "PUT", "/admin/book/1", `{"Pages": "many"}`, send...)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected %d got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	w = do(r, "GET", "/admin/book?format=json", "")
+	var list struct {
+		Items []Book
+		Total int
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if list.Total != 1 || len(list.Items) != 1 || list.Items[0].Pages != 20 {
+		t.Errorf("list: %s", w.Body)
+	}
+	if w.Header().Get("X-Total-Count") != "1" || w.Header().Get("Link") == "" {
+		t.Errorf("expected the pagination headers got %v", w.Header())
+	}
+
+	w = do(r, "GET", "/admin", "", accept, jsonType)
+	if !strings.Contains(w.Body.String(), `"path":"book"`) {
+		t.Errorf("home: %s", w.Body)
+	}
+
+	if w = do(r, "DELETE", "/admin/book/1", "", append(send, accept, jsonType)...); w.Code != http.StatusNoContent {
+		t.Errorf("delete: %d", w.Code)
+	}
+	if w = do(r, "GET", "/admin/book/1", "", accept, jsonType); w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestAdminOverride(t *testing.T) {
+	v, err := view.NewSimpleViewWithOptions("views", view.Options{FS: fstest.MapFS{
+		"views/admin/home.tpl": {Data: []byte(`{{range .Admin.Resources}}custom {{.Name}}{{end}}`)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := newRouter(t, v)
+	if w := do(r, "GET", "/admin", ""); w.Body.String() != "custom Book" {
+		t.Errorf("expected the application template got %s", w.Body)
+	}
+
+	// pages without an application template use the default ones.
+	if w := do(r, "GET", "/admin/book", ""); !strings.Contains(w.Body.String(), "<table>") {
+		t.Errorf("expected the default template got %s", w.Body)
+	}
+}
+
+func TestAdminCSRF(t *testing.T) {
+	r, db := newRouter(t, nil)
+	db.Create(&Book{Title: "Go"})
+	cookie, token := session(t, r)
+	_, other := session(t, r)
+	form := "application/x-www-form-urlencoded"
+	sample := []struct {
+		method, path, body string
+		header             []string
+	}{
+		{"POST", "/admin/book", "title=C", []string{"Content-Type", form}},
+		{"POST", "/admin/book", "title=C", []string{"Content-Type", form, "Cookie", cookie}},
+		{"POST", "/admin/book", "title=C&csrf_token=" + other, []string{"Content-Type", form, "Cookie", cookie}},
+		{"POST", "/admin/book", "title=C&csrf_token=" + token, []string{"Content-Type", form}},
+		{"POST", "/admin/book/1/delete", "", []string{"Cookie", cookie}},
+		{"PATCH", "/admin/book/1", `{"Title": "C"}`, []string{"Content-Type", "application/json", "Cookie", cookie, CSRFHeader, other}},
+		{"DELETE", "/admin/book/1", "", []string{"Cookie", cookie, "Accept", "application/json"}},
+	}
+	for _, s := range sample {
+		if w := do(r, s.method, s.path, s.body, s.header...); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s %v: expected %d got %d", s.method, s.path, s.header, http.StatusForbidden, w.Code)
+		}
+	}
+	var books []Book
+	db.Find(&books)
+	if len(books) != 1 || books[0].Title != "Go" {
+		t.Errorf("expected the books to be unchanged got %+v", books)
+	}
+}
+
+func TestAdminErrors(t *testing.T) {
+	r, db := newRouter(t, nil)
+	db.DropTable(&Book{})
+	for _, accept := range []string{"text/html", "application/json"} {
+		w := do(r, "GET", "/admin/book", "", "Accept", accept)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected %d got %d", accept, http.StatusInternalServerError, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "books") {
+			t.Errorf("%s: expected a generic error got %s", accept, w.Body)
+		}
+	}
+}
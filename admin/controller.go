@@ -0,0 +1,338 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/pagination"
+	"github.com/jinzhu/gorm"
+)
+
+// Controller serves the admin pages, it is created by Admin.Controller.
+type Controller struct {
+	controller.BaseController
+	Routes []string
+
+	admin *Admin
+}
+
+// Home lists the models.
+func (c *Controller) Home() {
+	p, ok := c.page(false)
+	if !ok {
+		return
+	}
+	if wantsJSON(c.Ctx.Request()) {
+		c.RenderJSON(map[string]interface{}{"resources": p.Resources}, http.StatusOK)
+		return
+	}
+	p.Title = "Admin"
+	c.render("home", p, http.StatusOK)
+}
+
+// List lists the values of a model. The query sets the page, the number of
+// values per page with per_page, the order with sort, e.g. sort=-created_at,
+// and filters by column, e.g. title=Go.
+func (c *Controller) List() {
+	p, ok := c.page(true)
+	if !ok {
+		return
+	}
+	res := p.Resource
+	var columns []string
+	for _, f := range res.Fields {
+		columns = append(columns, f.Column)
+	}
+	opts := pagination.Options{
+		PerPage:    c.admin.opts.PerPage,
+		Sortable:   columns,
+		Filterable: columns,
+	}
+	if res.pk != nil {
+		opts.DefaultSort = res.pk.Column
+	}
+	params := pagination.Parse(c.Ctx, opts)
+	items := res.NewSlice()
+	pager, err := params.Paginate(c.Ctx.DB.DB, items)
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	if wantsJSON(c.Ctx.Request()) {
+		pager.SetHeaders(c.Ctx)
+		data := pager.Meta()
+		data["items"] = items
+		c.RenderJSON(data, http.StatusOK)
+		return
+	}
+
+	list := reflect.ValueOf(items).Elem()
+	for i := 0; i < list.Len(); i++ {
+		p.Rows = append(p.Rows, newRow(res, list.Index(i).Addr().Interface()))
+	}
+	for _, f := range res.Fields {
+		p.Columns = append(p.Columns, &Column{
+			Field:   f,
+			SortURL: params.SortURL(f.Column),
+			Sorted:  params.Sorted(f.Column),
+		})
+	}
+	p.Filters = params.Filters
+	p.Sort = params.SortString()
+	p.Pager = pager
+	p.Title = res.Name
+	c.render("list", p, http.StatusOK)
+}
+
+// Show shows a value of a model.
+func (c *Controller) Show() {
+	p, item, ok := c.find(c.Ctx.DB.DB)
+	if !ok {
+		return
+	}
+	if wantsJSON(c.Ctx.Request()) {
+		c.RenderJSON(item, http.StatusOK)
+		return
+	}
+	p.Item = newRow(p.Resource, item)
+	p.Title = p.Resource.Name + " " + p.Item.ID
+	c.render("show", p, http.StatusOK)
+}
+
+// Add shows the form to create a value of a model.
+func (c *Controller) Add() {
+	p, ok := c.page(true)
+	if !ok {
+		return
+	}
+	p.Item = newRow(p.Resource, p.Resource.New())
+	p.Action = p.URL(p.Resource.Path)
+	p.Title = "New " + p.Resource.Name
+	c.render("form", p, http.StatusOK)
+}
+
+// Create creates a value of a model from the form or the JSON body.
+func (c *Controller) Create() {
+	p, ok := c.page(true)
+	if !ok {
+		return
+	}
+	item := p.Resource.New()
+	p.Action = p.URL(p.Resource.Path)
+	p.Title = "New " + p.Resource.Name
+	if !c.bind(p, item) {
+		return
+	}
+	if err := c.Ctx.DB.Primary().Create(item).Error; err != nil {
+		c.Ctx.Log.Errors(err)
+		c.invalid(p, item, errSave(p.Resource), nil)
+		return
+	}
+	if wantsJSON(c.Ctx.Request()) {
+		c.RenderJSON(item, http.StatusCreated)
+		return
+	}
+	c.Ctx.Redirect(p.URL(p.Resource.Path, p.Resource.ID(item)), http.StatusSeeOther)
+}
+
+// Edit shows the form to update a value of a model.
+func (c *Controller) Edit() {
+	p, item, ok := c.find(c.Ctx.DB.DB)
+	if !ok {
+		return
+	}
+	p.Item = newRow(p.Resource, item)
+	p.Action = p.URL(p.Resource.Path, p.Item.ID)
+	p.Title = "Edit " + p.Resource.Name + " " + p.Item.ID
+	c.render("form", p, http.StatusOK)
+}
+
+// Update updates a value of a model from the form or the JSON body.
+func (c *Controller) Update() {
+	db := c.Ctx.DB.Primary()
+	p, item, ok := c.find(db)
+	if !ok {
+		return
+	}
+	id := p.Resource.ID(item)
+	p.Action = p.URL(p.Resource.Path, id)
+	p.Title = "Edit " + p.Resource.Name + " " + id
+	if !c.bind(p, item) {
+		return
+	}
+	if err := db.Save(item).Error; err != nil {
+		c.Ctx.Log.Errors(err)
+		c.invalid(p, item, errSave(p.Resource), nil)
+		return
+	}
+	if wantsJSON(c.Ctx.Request()) {
+		c.RenderJSON(item, http.StatusOK)
+		return
+	}
+	c.Ctx.Redirect(p.URL(p.Resource.Path, id), http.StatusSeeOther)
+}
+
+// Delete deletes a value of a model.
+func (c *Controller) Delete() {
+	db := c.Ctx.DB.Primary()
+	p, item, ok := c.find(db)
+	if !ok {
+		return
+	}
+	if err := db.Delete(item).Error; err != nil {
+		c.fail(err)
+		return
+	}
+	if wantsJSON(c.Ctx.Request()) {
+		c.Ctx.Set(http.StatusNoContent)
+		return
+	}
+	c.Ctx.Redirect(p.URL(p.Resource.Path), http.StatusSeeOther)
+}
+
+// page returns the page of the request. When withResource is true the
+// resource named by the model parameter is set, and the response is not found
+// when there is no such resource. Requests changing data are forbidden without
+// the CSRF token of the session.
+func (c *Controller) page(withResource bool) (*Page, bool) {
+	if c.Ctx.DB == nil || !c.Ctx.DB.IsOpen() {
+		c.fail(fmt.Errorf("utron: the admin needs a database"))
+		return nil, false
+	}
+	token, ok := c.checkCSRF()
+	if !ok {
+		return nil, false
+	}
+	if token != "" {
+		c.Ctx.SetHeader(CSRFHeader, token)
+	}
+	p := &Page{
+		Prefix:    c.admin.opts.Prefix,
+		Resources: c.admin.resources(c.Ctx.DB.DB, c.Ctx.DB.Registered()),
+		CSRFToken: token,
+	}
+	if !withResource {
+		return p, true
+	}
+	name := c.Ctx.Params["model"]
+	for _, res := range p.Resources {
+		if res.Path == name {
+			p.Resource = res
+			return p, true
+		}
+	}
+	c.notFound()
+	return nil, false
+}
+
+// find returns the page of the request and the model value with the id
+// parameter, loaded with db.
+func (c *Controller) find(db *gorm.DB) (*Page, interface{}, bool) {
+	p, ok := c.page(true)
+	if !ok {
+		return nil, nil, false
+	}
+	pk := p.Resource.pk
+	if pk == nil {
+		c.notFound()
+		return nil, nil, false
+	}
+	item := p.Resource.New()
+	err := db.Where(fmt.Sprintf("%s = ?", db.Dialect().Quote(pk.Column)), c.Ctx.Params["id"]).First(item).Error
+	if err == gorm.ErrRecordNotFound {
+		c.notFound()
+		return nil, nil, false
+	}
+	if err != nil {
+		c.fail(err)
+		return nil, nil, false
+	}
+	return p, item, true
+}
+
+// bind sets the model value item from the request, the read only fields are
+// kept for JSON bodies. It responds with the errors and returns false when the
+// request is invalid.
+func (c *Controller) bind(p *Page, item interface{}) bool {
+	r := c.Ctx.Request()
+	if isJSON(r) {
+		keep := p.Resource.New()
+		p.Resource.keepReadOnly(keep, item)
+		if err := decodeJSON(r, item); err != nil {
+			c.invalid(p, item, err.Error(), nil)
+			return false
+		}
+		p.Resource.keepReadOnly(item, keep)
+		return true
+	}
+	if err := r.ParseForm(); err != nil {
+		c.invalid(p, item, err.Error(), nil)
+		return false
+	}
+	if errs := p.Resource.setForm(item, r.PostForm); len(errs) > 0 {
+		c.invalid(p, item, "", errs)
+		return false
+	}
+	return true
+}
+
+// invalid responds to a request which could not be saved, with the form for
+// HTML requests.
+func (c *Controller) invalid(p *Page, item interface{}, msg string, errs map[string]string) {
+	if wantsJSON(c.Ctx.Request()) {
+		c.RenderJSON(errorJSON{Error: msg, Errors: errs}, http.StatusUnprocessableEntity)
+		return
+	}
+	p.Item = newRow(p.Resource, item)
+	form := c.Ctx.Request().PostForm
+	for i, f := range p.Resource.Fields {
+		if _, ok := errs[f.Name]; ok {
+			p.Item.Values[i] = form.Get(f.Column)
+		}
+	}
+	p.Error = msg
+	p.Errors = errs
+	c.render("form", p, http.StatusUnprocessableEntity)
+}
+
+// errSave is the message of a model value the database failed to save.
+func errSave(res *Resource) string {
+	return "the " + res.Name + " could not be saved"
+}
+
+// render renders the page with the template named admin/name of the
+// application view, or with the default template when there is none.
+func (c *Controller) render(name string, p *Page, status int) {
+	c.Ctx.Data["Admin"] = p
+	if v, ok := c.Ctx.View().(interface{ Lookup(string) bool }); ok && v.Lookup("admin/"+name) {
+		c.Ctx.Template = "admin/" + name
+	} else {
+		c.Ctx.Set(c.admin.view)
+		c.Ctx.Template = name
+		c.Ctx.Layout = ""
+	}
+	c.HTML(status)
+}
+
+func (c *Controller) notFound() {
+	if wantsJSON(c.Ctx.Request()) {
+		c.RenderJSON(errorJSON{Error: "not found"}, http.StatusNotFound)
+		return
+	}
+	c.String(http.StatusNotFound)
+	_, _ = c.Ctx.Write([]byte(http.StatusText(http.StatusNotFound)))
+}
+
+// fail logs err and responds with an internal server error, the error is not
+// shown as it may contain details of the database.
+func (c *Controller) fail(err error) {
+	c.Ctx.Log.Errors(err)
+	if wantsJSON(c.Ctx.Request()) {
+		c.RenderJSON(errorJSON{Error: "internal server error"}, http.StatusInternalServerError)
+		return
+	}
+	c.String(http.StatusInternalServerError)
+	_, _ = c.Ctx.Write([]byte(http.StatusText(http.StatusInternalServerError)))
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+)
+
+const (
+	// CSRFField is the form field carrying the CSRF token.
+	CSRFField = "csrf_token"
+
+	// CSRFHeader is the header carrying the CSRF token, it is set on every
+	// response so JSON clients can send it back.
+	CSRFHeader = "X-CSRF-Token"
+
+	// csrfKey is the key of the token in the session.
+	csrfKey = "_admin_csrf"
+)
+
+var errCSRF = errors.New("utron: missing or invalid admin CSRF token")
+
+// csrfToken returns the CSRF token of the session, a new token is stored in
+// the session when it has none.
+func (c *Controller) csrfToken() (string, error) {
+	s, err := c.Ctx.Session()
+	if err != nil {
+		return "", err
+	}
+	if token, ok := s.Get(csrfKey).(string); ok && token != "" {
+		return token, nil
+	}
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	s.Set(csrfKey, token)
+	return token, nil
+}
+
+// checkCSRF returns the CSRF token of the session. Requests which are not GET
+// or HEAD must send the token in the form or the header, otherwise they are
+// forbidden and false is returned. There is no token without a session
+// store, so such requests are always forbidden.
+func (c *Controller) checkCSRF() (string, bool) {
+	r := c.Ctx.Request()
+	token, err := c.csrfToken()
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if err != nil {
+			c.Ctx.Log.Errors(err)
+		}
+		return token, true
+	}
+	sent := r.Header.Get(CSRFHeader)
+	if sent == "" && !isJSON(r) {
+		sent = r.PostFormValue(CSRFField)
+	}
+	if err != nil || sent == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+		if err != nil {
+			c.Ctx.Log.Errors(err)
+		}
+		c.forbidden()
+		return "", false
+	}
+	return token, true
+}
+
+func (c *Controller) forbidden() {
+	c.Ctx.Log.Errors(errCSRF)
+	if wantsJSON(c.Ctx.Request()) {
+		c.RenderJSON(errorJSON{Error: "forbidden"}, http.StatusForbidden)
+		return
+	}
+	c.String(http.StatusForbidden)
+	_, _ = c.Ctx.Write([]byte(http.StatusText(http.StatusForbidden)))
+}
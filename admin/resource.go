@@ -0,0 +1,260 @@
+package admin
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// timeLayouts are the formats accepted for time fields in forms, the first one
+// is used by the datetime-local inputs of the default templates.
+var timeLayouts = []string{"2006-01-02T15:04", time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// readOnly are fields managed by gorm, which are never set from forms.
+var readOnly = map[string]bool{"CreatedAt": true, "UpdatedAt": true, "DeletedAt": true}
+
+// Resource is a registered model managed by the admin.
+type Resource struct {
+	// Name is the name of the model type, e.g. Book.
+	Name string `json:"name"`
+
+	// Path is the name in URLs, e.g. book.
+	Path string `json:"path"`
+
+	// Fields are the columns of the model.
+	Fields []*Field `json:"fields"`
+
+	typ reflect.Type
+	pk  *Field
+}
+
+// Field is a column of a Resource.
+type Field struct {
+	Name     string `json:"name"`
+	Column   string `json:"column"`
+	Primary  bool   `json:"primary"`
+	ReadOnly bool   `json:"read_only"`
+
+	// Input is the type of the HTML input used to edit the field.
+	Input string `json:"input"`
+}
+
+// resources returns the resources for the registered models, sorted by name.
+func resources(db *gorm.DB, registered map[string]reflect.Type) []*Resource {
+	var list []*Resource
+	for name, typ := range registered {
+		list = append(list, newResource(db, name, typ))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func newResource(db *gorm.DB, name string, typ reflect.Type) *Resource {
+	r := &Resource{
+		Name: name,
+		Path: strings.ToLower(name),
+		typ:  typ,
+	}
+	scope := db.NewScope(reflect.New(typ).Interface())
+	for _, sf := range scope.GetModelStruct().StructFields {
+		if !sf.IsNormal || sf.IsIgnored {
+			continue
+		}
+		f := &Field{
+			Name:     sf.Name,
+			Column:   sf.DBName,
+			Primary:  sf.IsPrimaryKey,
+			ReadOnly: sf.IsPrimaryKey || readOnly[sf.Name],
+			Input:    inputType(sf.Struct.Type),
+		}
+		if f.Primary && r.pk == nil {
+			r.pk = f
+		}
+		r.Fields = append(r.Fields, f)
+	}
+	return r
+}
+
+// New returns a pointer to a new value of the model.
+func (r *Resource) New() interface{} {
+	return reflect.New(r.typ).Interface()
+}
+
+// NewSlice returns a pointer to an empty slice of the model.
+func (r *Resource) NewSlice() interface{} {
+	return reflect.New(reflect.SliceOf(r.typ)).Interface()
+}
+
+// Field returns the field with the column or name, if any.
+func (r *Resource) Field(name string) *Field {
+	for _, f := range r.Fields {
+		if f.Column == name || f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// ID returns the primary key of the model value v.
+func (r *Resource) ID(v interface{}) string {
+	if r.pk == nil {
+		return ""
+	}
+	return r.pk.Format(v)
+}
+
+// Format returns the value of f in the model value v, formatted for display.
+func (f *Field) Format(v interface{}) string {
+	fv := reflect.Indirect(reflect.ValueOf(v)).FieldByName(f.Name)
+	if !fv.IsValid() {
+		return ""
+	}
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return ""
+		}
+		fv = fv.Elem()
+	}
+	switch x := fv.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		if f.Input == "datetime-local" {
+			return x.Format(timeLayouts[0])
+		}
+		return x.Format(time.RFC3339)
+	}
+	return fmt.Sprint(fv.Interface())
+}
+
+// inputType returns the HTML input type for values of typ.
+func inputType(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "datetime-local"
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "checkbox"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "text"
+}
+
+// setForm sets the editable fields of the model value v from form. It returns
+// the errors by field name.
+func (r *Resource) setForm(v interface{}, form url.Values) map[string]string {
+	errs := make(map[string]string)
+	rv := reflect.ValueOf(v).Elem()
+	for _, f := range r.Fields {
+		if f.ReadOnly {
+			continue
+		}
+		values, ok := form[f.Column]
+		if !ok && f.Input != "checkbox" {
+			continue
+		}
+		s := ""
+		if len(values) > 0 {
+			s = values[len(values)-1]
+		}
+		if err := setValue(rv.FieldByName(f.Name), s); err != nil {
+			errs[f.Name] = err.Error()
+		}
+	}
+	return errs
+}
+
+// setValue parses s into the field value fv.
+func setValue(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.Ptr {
+		if s == "" {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		n := reflect.New(fv.Type().Elem())
+		if err := setValue(n.Elem(), s); err != nil {
+			return err
+		}
+		fv.Set(n)
+		return nil
+	}
+	if fv.Type() == reflect.TypeOf(time.Time{}) {
+		if s == "" {
+			fv.Set(reflect.ValueOf(time.Time{}))
+			return nil
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				fv.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not a valid time", s)
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		fv.SetBool(s == "on" || s == "true" || s == "1")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			fv.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid number", s)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			fv.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid number", s)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			fv.SetFloat(0)
+			return nil
+		}
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid number", s)
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("%s fields can not be edited", fv.Type())
+	}
+	return nil
+}
+
+// keepReadOnly copies the read only fields of the model value src to dst, so
+// they are not changed by decoded JSON.
+func (r *Resource) keepReadOnly(dst, src interface{}) {
+	d := reflect.ValueOf(dst).Elem()
+	s := reflect.ValueOf(src).Elem()
+	for _, f := range r.Fields {
+		if f.ReadOnly {
+			d.FieldByName(f.Name).Set(s.FieldByName(f.Name))
+		}
+	}
+}
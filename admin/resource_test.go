@@ -0,0 +1,64 @@
+package admin
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Event struct {
+	ID       uint
+	Name     string
+	Seats    *int
+	Price    float64
+	Open     bool
+	StartsAt time.Time
+	Ignored  string `gorm:"-"`
+}
+
+func TestResource(t *testing.T) {
+	_, db := newRouter(t, nil)
+	res := newResource(db.DB, "Event", reflect.TypeOf(Event{}))
+	if res.Path != "event" || res.pk == nil || res.pk.Column != "id" {
+		t.Fatalf("unexpected resource %+v", res)
+	}
+	if res.Field("Ignored") != nil {
+		t.Error("expected ignored fields to be skipped")
+	}
+	inputs := map[string]string{
+		"name": "text", "seats": "number", "price": "number",
+		"open": "checkbox", "starts_at": "datetime-local",
+	}
+	for col, input := range inputs {
+		if f := res.Field(col); f == nil || f.Input != input {
+			t.Errorf("%s: expected input %s got %+v", col, input, f)
+		}
+	}
+
+	ev := &Event{ID: 3, Open: true}
+	errs := res.setForm(ev, url.Values{
+		"id":        {"7"},
+		"name":      {"Launch"},
+		"seats":     {"40"},
+		"price":     {"9.5"},
+		"starts_at": {"2020-01-02T15:04"},
+	})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if ev.ID != 3 || ev.Name != "Launch" || *ev.Seats != 40 || ev.Price != 9.5 || ev.Open {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if got := res.Field("starts_at").Format(ev); got != "2020-01-02T15:04" {
+		t.Errorf("expected the time in the input format got %s", got)
+	}
+	if res.ID(ev) != "3" {
+		t.Errorf("expected id 3 got %s", res.ID(ev))
+	}
+
+	errs = res.setForm(ev, url.Values{"seats": {""}, "price": {"cheap"}, "starts_at": {"soon"}})
+	if ev.Seats != nil || len(errs) != 2 || errs["Price"] == "" || errs["StartsAt"] == "" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
@@ -0,0 +1,27 @@
+{{/* layout: admin */}}
+{{- $a := .Admin}}
+<h1>{{$a.Title}}</h1>
+{{- if $a.Error}}
+<p class="error">{{$a.Error}}</p>
+{{- end}}
+<form method="post" action="{{$a.Action}}">
+<input type="hidden" name="csrf_token" value="{{$a.CSRFToken}}">
+{{- range $i, $f := $a.Resource.Fields}}
+{{- if not $f.ReadOnly}}
+{{- $v := index $a.Item.Values $i}}
+<p>
+<label for="{{$f.Column}}">{{$f.Name}}</label>
+{{- if eq $f.Input "checkbox"}}
+<input type="checkbox" id="{{$f.Column}}" name="{{$f.Column}}"{{if eq $v "true"}} checked{{end}}>
+{{- else}}
+<input type="{{$f.Input}}" id="{{$f.Column}}" name="{{$f.Column}}" value="{{$v}}"{{if eq $f.Input "number"}} step="any"{{end}}>
+{{- end}}
+{{- with index $a.Errors $f.Name}}
+<span class="error">{{.}}</span>
+{{- end}}
+</p>
+{{- end}}
+{{- end}}
+<button type="submit">Save</button>
+<a href="{{$a.URL $a.Resource.Path}}">Cancel</a>
+</form>
@@ -0,0 +1,9 @@
+{{/* layout: admin */}}
+<h1>{{.Admin.Title}}</h1>
+<ul>
+{{- range .Admin.Resources}}
+<li><a href="{{$.Admin.URL .Path}}">{{.Name}}</a></li>
+{{- else}}
+<li>No models are registered.</li>
+{{- end}}
+</ul>
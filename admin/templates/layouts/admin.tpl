@@ -0,0 +1,28 @@
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Admin.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 0; color: #222; }
+nav { background: #333; padding: .5em 1em; }
+nav a { color: #fff; margin-right: 1em; text-decoration: none; }
+main { padding: 1em; }
+table { border-collapse: collapse; }
+th, td { border-bottom: 1px solid #ddd; padding: .3em .6em; text-align: left; }
+form.inline { display: inline; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<nav>
+<a href="{{.Admin.URL}}">Admin</a>
+{{- range .Admin.Resources}}
+<a href="{{$.Admin.URL .Path}}">{{.Name}}</a>
+{{- end}}
+</nav>
+<main>
+{{yield}}
+</main>
+</body>
+</html>
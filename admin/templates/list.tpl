@@ -0,0 +1,51 @@
+{{/* layout: admin */}}
+{{- $a := .Admin}}
+<h1>{{$a.Title}}</h1>
+<p><a href="{{$a.URL $a.Resource.Path "new"}}">New {{$a.Resource.Name}}</a></p>
+<form method="get" action="{{$a.URL $a.Resource.Path}}">
+{{- if $a.Sort}}
+<input type="hidden" name="sort" value="{{$a.Sort}}">
+{{- end}}
+{{- range $a.Resource.Fields}}
+{{- if ne .Input "datetime-local"}}
+<label>{{.Name}} <input type="text" name="{{.Column}}" value="{{index $a.Filters .Column}}"></label>
+{{- end}}
+{{- end}}
+<button type="submit">Filter</button>
+</form>
+<table>
+<thead>
+<tr>
+{{- range $a.Columns}}
+<th><a href="{{.SortURL}}">{{.Name}}</a>{{if eq .Sorted "asc"}} &#9650;{{else if eq .Sorted "desc"}} &#9660;{{end}}</th>
+{{- end}}
+<th></th>
+</tr>
+</thead>
+<tbody>
+{{- range $a.Rows}}
+<tr>
+{{- range .Values}}
+<td>{{.}}</td>
+{{- end}}
+<td>
+<a href="{{$a.URL $a.Resource.Path .ID}}">Show</a>
+<a href="{{$a.URL $a.Resource.Path .ID "edit"}}">Edit</a>
+<form class="inline" method="post" action="{{$a.URL $a.Resource.Path .ID "delete"}}"><input type="hidden" name="csrf_token" value="{{$a.CSRFToken}}"><button type="submit">Delete</button></form>
+</td>
+</tr>
+{{- else}}
+<tr><td colspan="{{len $a.Columns}}">Nothing found.</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- with $a.Pager}}
+<p>
+{{- if .HasPrev}}<a href="{{.PrevURL}}">Previous</a> {{end}}
+{{- range .Links 2}}
+{{- if .Gap}} &hellip;{{else if .Current}} <strong>{{.Number}}</strong>{{else}} <a href="{{.URL}}">{{.Number}}</a>{{end}}
+{{- end}}
+{{- if .HasNext}} <a href="{{.NextURL}}">Next</a>{{end}}
+</p>
+<p>{{.Total}} total</p>
+{{- end}}
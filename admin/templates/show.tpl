@@ -0,0 +1,14 @@
+{{/* layout: admin */}}
+{{- $a := .Admin}}
+<h1>{{$a.Title}}</h1>
+<dl>
+{{- range $i, $f := $a.Resource.Fields}}
+<dt>{{$f.Name}}</dt>
+<dd>{{index $a.Item.Values $i}}</dd>
+{{- end}}
+</dl>
+<p>
+<a href="{{$a.URL $a.Resource.Path $a.Item.ID "edit"}}">Edit</a>
+<a href="{{$a.URL $a.Resource.Path}}">Back</a>
+</p>
+<form method="post" action="{{$a.URL $a.Resource.Path $a.Item.ID "delete"}}"><input type="hidden" name="csrf_token" value="{{$a.CSRFToken}}"><button type="submit">Delete</button></form>
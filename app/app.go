@@ -1,17 +1,25 @@
 package app
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/gernest/qlstore"
+	"github.com/gernest/utron/assets"
 	"github.com/gernest/utron/config"
 	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/i18n"
 	"github.com/gernest/utron/logger"
+	"github.com/gernest/utron/migrate"
 	"github.com/gernest/utron/models"
 	"github.com/gernest/utron/router"
 	"github.com/gernest/utron/view"
@@ -36,16 +44,50 @@ type App struct {
 	ConfigPath   string
 	StaticServer StaticServerFunc
 	SessionStore sessions.Store
-	isInit       bool
+
+	// DBs are the named databases of Config.Databases.
+	DBs map[string]*models.Model
+
+	// ViewFuncs are template functions made available to the views. They must
+	// be set before Init is called.
+	ViewFuncs template.FuncMap
+
+	// FS holds the views and static files when Config.Embedded is true, e.g.
+	// an embed.FS. The ViewsDir and StaticDir are paths in FS.
+	FS fs.FS
+
+	// Assets serves the static files, and builds their fingerprinted paths for
+	// the asset template function. It is set by Init when StaticDir is set.
+	Assets *assets.Manager
+
+	// I18n holds the message catalogs loaded from the LocalesDir. It is nil
+	// when the directory does not exist.
+	I18n *i18n.Bundle
+
+	// Migrations are the versioned database migrations. Go migrations are
+	// added before Init, and SQL migrations are loaded from the MigrationsDir.
+	Migrations *migrate.Migrator
+
+	isInit           bool
+	cleaner          *sessionCleaner
+	sessionCloser    io.Closer
+	migrationsLoaded bool
+	server           server
+	reload           reloader
+	defaultLog       logger.Logger
+	logLevelHook     bool
 }
 
 // NewApp creates a new bare-bone utron application. To use the MVC components, you should call
 // the Init method before serving requests.
 func NewApp() *App {
+	l := logger.NewDefaultLogger(os.Stdout)
 	return &App{
-		Log:    logger.NewDefaultLogger(os.Stdout),
-		Router: router.NewRouter(),
-		Model:  models.NewModel(),
+		Log:        l,
+		Router:     router.NewRouter(),
+		Model:      models.NewModel(),
+		Migrations: migrate.New(nil),
+		defaultLog: l,
 	}
 }
 
@@ -74,13 +116,136 @@ func StaticServer(cfg *config.Config) (string, bool, http.Handler) {
 	return "", false, nil
 }
 
+// viewFuncs returns the template functions bound to the App, followed by the
+// ViewFuncs.
+func (a *App) viewFuncs() template.FuncMap {
+	funcs := template.FuncMap{
+		"asset": func(name string) string {
+			if a.Assets != nil {
+				return a.Assets.Path(name)
+			}
+			return path.Join("/static", name)
+		},
+		"url": func(name string, pairs ...string) (string, error) {
+			return a.Router.URL(name, pairs...)
+		},
+		"t": func(locale, key string, args ...interface{}) string {
+			if a.I18n != nil {
+				return a.I18n.T(locale, key, args...)
+			}
+			return key
+		},
+	}
+	for k, v := range a.ViewFuncs {
+		funcs[k] = v
+	}
+	return funcs
+}
+
+// dirFS returns the file system of the directory dir, either on disk or in FS
+// when Config.Embedded is true.
+func (a *App) dirFS(dir string) (fs.FS, error) {
+	if a.Config.Embedded {
+		sub, err := fs.Sub(a.FS, path.Clean(dir))
+		if err != nil {
+			return nil, err
+		}
+		if _, err = fs.Stat(sub, "."); err != nil {
+			return nil, err
+		}
+		return sub, nil
+	}
+	abs, err := getAbsolutePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return os.DirFS(abs), nil
+}
+
+// loadLocales loads the message catalogs from the LocalesDir, translations are
+// disabled when it does not exist.
+func (a *App) loadLocales() error {
+	dir := a.Config.LocalesDir
+	if dir == "" {
+		dir = "locales"
+	}
+	locales, err := a.dirFS(dir)
+	if err != nil {
+		return nil
+	}
+	lang := a.Config.DefaultLocale
+	if lang == "" {
+		lang = "en"
+	}
+	bundle := i18n.NewBundle(lang)
+	if err = bundle.Load(locales); err != nil {
+		return err
+	}
+	a.I18n = bundle
+	return nil
+}
+
+// ValidateConfig validates cfg like Config.Validate does, and also checks the
+// view engines, which are only known to the App. It returns a
+// *config.ValidationError listing all the problems.
+func ValidateConfig(cfg *config.Config) error {
+	var problems []config.Problem
+	if err := cfg.Validate(); err != nil {
+		verr, ok := err.(*config.ValidationError)
+		if !ok {
+			return err
+		}
+		problems = verr.Problems
+	}
+	exts := make([]string, 0, len(cfg.ViewEngines))
+	for ext := range cfg.ViewEngines {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		if _, ok := view.EngineByName(cfg.ViewEngines[ext]); !ok {
+			key := "view_engines." + ext
+			problems = append(problems, config.Problem{
+				File:    cfg.Source(key),
+				Key:     key,
+				Message: fmt.Sprintf("unknown engine %q, supported are html, text and mustache", cfg.ViewEngines[ext]),
+			})
+		}
+	}
+	if len(problems) > 0 {
+		return &config.ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// viewEngines returns the default template engines, with the ones set in
+// cfg.ViewEngines applied.
+func viewEngines(cfg *config.Config) (map[string]view.Engine, error) {
+	engines := view.DefaultEngines()
+	for ext, name := range cfg.ViewEngines {
+		e, ok := view.EngineByName(name)
+		if !ok {
+			return nil, fmt.Errorf("utron: unknown view engine %s for %s", name, ext)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		engines[ext] = e
+	}
+	return engines, nil
+}
+
 func (a *App) options() *router.Options {
 	return &router.Options{
 		Model:        a.Model,
+		DBs:          a.DBs,
 		View:         a.View,
 		Config:       a.Config,
+		ConfigFunc:   a.CurrentConfig,
 		Log:          a.Log,
 		SessionStore: a.SessionStore,
+		I18n:         a.I18n,
+		Transaction:  a.Config.TransactionPerRequest && !a.Config.NoModel,
 	}
 }
 
@@ -92,6 +257,49 @@ func (a *App) Init() error {
 	return a.init()
 }
 
+// configureLog applies Config.LogFormat and Config.LogLevel. The format only
+// replaces the logger created by NewApp, a Log set by the application is kept.
+// The level, when set, follows the reloads of the configuration, it goes back
+// to LevelInfo when log_level is removed.
+func (a *App) configureLog() error {
+	cfg := a.Config
+	if cfg.LogFormat != "" && a.Log == a.defaultLog {
+		l, err := logger.New(os.Stdout, logger.Options{Format: cfg.LogFormat})
+		if err != nil {
+			return err
+		}
+		a.Log = l
+	}
+	if !a.logLevelHook {
+		a.logLevelHook = true
+		a.OnConfigChange(func(cfg *config.Config) {
+			_ = a.setLogLevel(cfg.LogLevel)
+		})
+	}
+	if cfg.LogLevel == "" {
+		return nil
+	}
+	return a.setLogLevel(cfg.LogLevel)
+}
+
+// setLogLevel sets the level of the Log when it is leveled, an empty name is
+// LevelInfo.
+func (a *App) setLogLevel(name string) error {
+	l, ok := a.Log.(logger.Leveled)
+	if !ok {
+		return nil
+	}
+	level := logger.LevelInfo
+	if name != "" {
+		var err error
+		if level, err = logger.ParseLevel(name); err != nil {
+			return err
+		}
+	}
+	l.SetLevel(level)
+	return nil
+}
+
 // SetConfigPath sets the directory path to search for the config files.
 func (a *App) SetConfigPath(dir string) {
 	a.ConfigPath = dir
@@ -103,77 +311,110 @@ func (a *App) init() error {
 	if err != nil {
 		return err
 	}
+	if err = ValidateConfig(appConfig); err != nil {
+		return err
+	}
 	a.Config = appConfig
+	if err = a.configureLog(); err != nil {
+		return err
+	}
+	if appConfig.KeysGenerated() {
+		a.Log.Warn("utron: WARNING session_key_pair is not set, random session keys were generated. " +
+			"Sessions and flash messages are lost when the App restarts, and are not shared between instances. " +
+			"Set session_key_pair, e.g. to file: references to secret files.")
+	}
 
-	views, err := view.NewSimpleView(appConfig.ViewsDir)
+	engines, err := viewEngines(appConfig)
+	if err != nil {
+		return err
+	}
+	if appConfig.Embedded && a.FS == nil {
+		return errors.New("utron: embedded is set but the App has no FS")
+	}
+	if err = a.loadLocales(); err != nil {
+		return err
+	}
+	opts := view.Options{
+		Reload:  appConfig.Dev,
+		Funcs:   a.viewFuncs(),
+		Engines: engines,
+	}
+	if appConfig.Embedded {
+		opts.FS = a.FS
+	}
+	views, err := view.NewSimpleViewWithOptions(appConfig.ViewsDir, opts)
 	if err != nil {
 		return err
 	}
 	a.View = views
 
-	// only when mode is allowed
+	// only when mode is allowed. The Model is kept, so models registered
+	// before Init are migrated.
+	if a.Model == nil {
+		a.Model = models.NewModel()
+	}
 	if !appConfig.NoModel {
-		model := models.NewModel()
-		err = model.OpenWithConfig(appConfig)
-		if err != nil {
+		if !a.Model.IsOpen() {
+			if err = a.Model.OpenWithConfig(appConfig); err != nil {
+				return err
+			}
+		}
+		if err = a.loadMigrations(); err != nil {
 			return err
 		}
-		a.Model = model
+		if appConfig.Automigrate {
+			a.Model.AutoMigrateAll()
+		}
+		if appConfig.AutoMigrateVersions {
+			if _, err = a.Migrations.Up(); err != nil {
+				return err
+			}
+		}
+	}
+	if err = a.openDatabases(); err != nil {
+		return err
 	}
 
-	// The sessionistore s really not critical. The application can just run
-	// without session set
-	store, err := getSesionStore(appConfig)
-	if err == nil {
-		a.SessionStore = store
+	store, purge, closer, err := getSesionStore(appConfig)
+	if err != nil {
+		return err
+	}
+	a.SessionStore = store
+	if err = a.closeSessionStore(); err != nil {
+		a.Log.Errors("utron: closing the previous session store ", err)
+	}
+	a.sessionCloser = closer
+	if purge != nil && appConfig.SessionCleanupInterval >= 0 {
+		interval := appConfig.SessionCleanupInterval
+		if interval == 0 {
+			interval = defaultCleanupInterval
+		}
+		a.cleaner = newSessionCleaner(purge, time.Duration(interval)*time.Second, a.Log)
+		a.cleaner.start()
 	}
 
 	a.Router.Options = a.options()
-	a.Router.LoadRoutes(a.ConfigPath) // Load a routes file if available.	
+	a.Router.LoadRoutes(a.ConfigPath) // Load a routes file if available.
 	a.isInit = true
 
 	// In case the StaticDir is specified in the Config file, register
 	// a handler serving contents of that directory under the PathPrefix /static/.
+	// Files are fingerprinted unless in development mode, where they change
+	// without restarting.
 	if appConfig.StaticDir != "" {
-		static, _ := getAbsolutePath(appConfig.StaticDir)
-		if static != "" {
-			a.Router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(static))))
+		static, _ := a.dirFS(appConfig.StaticDir)
+		if static != nil {
+			m, err := a.Router.Assets("/static/", static, assets.Options{Dev: appConfig.Dev})
+			if err != nil {
+				return err
+			}
+			a.Assets = m
 		}
 
 	}
 	return nil
 }
 
-func getSesionStore(cfg *config.Config) (sessions.Store, error) {
-	opts := &sessions.Options{
-		Path:     cfg.SessionPath,
-		Domain:   cfg.SessionDomain,
-		MaxAge:   cfg.SessionMaxAge,
-		Secure:   cfg.SessionSecure,
-		HttpOnly: cfg.SessionSecure,
-	}
-	db, err := sql.Open("ql-mem", "session.db")
-	if err != nil {
-		return nil, err
-	}
-	err = qlstore.Migrate(db)
-	if err != nil {
-		return nil, err
-	}
-
-	store := qlstore.NewQLStore(db, "/", 2592000, keyPairs(cfg.SessionKeyPair)...)
-	store.Options = opts
-	return store, nil
-}
-
-func keyPairs(src []string) [][]byte {
-	var pairs [][]byte
-	for _, v := range src {
-		pairs = append(pairs, []byte(v))
-	}
-	return pairs
-}
-
 // getAbsolutePath returns the absolute path to dir. If the dir is relative, then we add
 // the current working directory. Checks are made to ensure the directory exist.
 // In case of any error, an empty string is returned.
@@ -204,6 +445,9 @@ func getAbsolutePath(dir string) (string, error) {
 // loadConfig loads the configuration file. If cfg is provided, then it is used as the directory
 // for searching the configuration files. It defaults to the directory named config in the current
 // working directory.
+//
+// The profile named by the UTRON_ENV environment variable, e.g. app.production.toml, is merged
+// over app.toml. See config.LoadProfile.
 func loadConfig(cfg ...string) (*config.Config, error) {
 	cfgDir := "config"
 	if len(cfg) > 0 {
@@ -211,38 +455,144 @@ func loadConfig(cfg ...string) (*config.Config, error) {
 	}
 
 	// Load configurations.
-	cfgFile, err := findConfigFile(cfgDir, "app")
-	if err != nil {
-		return nil, err
+	return config.LoadProfile(cfgDir, "app", "")
+}
+
+// AddController registers a controller, and middlewares if any is provided.
+func (a *App) AddController(ctrlfn func() controller.Controller, middlewares ...interface{}) {
+	_ = a.Router.Add(ctrlfn, middlewares...)
+}
+
+// ServeHTTP serves http requests. It can be used with other http.Handler implementations.
+//
+// When Config.LocaleURLPrefix is true, the locale prefix is removed from the
+// URL path before routing. The Strict-Transport-Security header is set on
+// HTTPS responses when Config.HSTSMaxAge is set.
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cfg := a.CurrentConfig()
+	setHSTS(cfg, w, r)
+	if a.I18n != nil && cfg.LocaleURLPrefix {
+		a.I18n.Middleware(a.Router).ServeHTTP(w, r)
+		return
 	}
-	return config.NewConfig(cfgFile)
+	a.Router.ServeHTTP(w, r)
 }
 
-// findConfigFile finds the configuration file name in the directory dir.
-func findConfigFile(dir string, name string) (file string, err error) {
-	extensions := []string{".json", ".toml", ".yml", ".hcl"}
+// openDatabases opens the named databases.
+func (a *App) openDatabases() error {
+	for name, dc := range a.Config.Databases {
+		if _, ok := a.DBs[name]; ok {
+			continue
+		}
+		db := models.NewModel()
+		if err := db.Open(dc); err != nil {
+			return fmt.Errorf("utron: opening database %s %v", name, err)
+		}
+		if a.DBs == nil {
+			a.DBs = make(map[string]*models.Model)
+		}
+		a.DBs[name] = db
+	}
+	return nil
+}
+
+// loadMigrations points the Migrations to the database, and adds the SQL
+// migrations found in the MigrationsDir.
+func (a *App) loadMigrations() error {
+	if a.Migrations == nil {
+		a.Migrations = migrate.New(nil)
+	}
+	a.Migrations.DB = a.Model.Primary()
+	if a.migrationsLoaded {
+		return nil
+	}
+	dir := a.Config.MigrationsDir
+	if dir == "" {
+		dir = "migrations"
+	}
+	if migrations, err := a.dirFS(dir); err == nil {
+		if err = a.Migrations.Load(migrations); err != nil {
+			return err
+		}
+	}
+	a.migrationsLoaded = true
+	return nil
+}
 
-	for _, ext := range extensions {
-		file = filepath.Join(dir, name)
-		if info, serr := os.Stat(file); serr == nil && !info.IsDir() {
-			return
+// Migrate runs the migrate command args, e.g. up, down or status, and writes
+// the result to out, see migrate.Migrator.Run. The App does not need to be
+// initialized, only the configuration and the database are loaded. The
+// configuration is validated like Init does.
+//
+// This is how utron migrate is implemented. Applications with Go migrations
+// call Migrate from their main function after adding them to Migrations.
+func (a *App) Migrate(args []string, out io.Writer) error {
+	if a.Config == nil {
+		if a.ConfigPath == "" {
+			a.SetConfigPath("config")
+		}
+		cfg, err := loadConfig(a.ConfigPath)
+		if err != nil {
+			return err
 		}
-		file = file + ext
-		if info, serr := os.Stat(file); serr == nil && !info.IsDir() {
-			return
+		if err = ValidateConfig(cfg); err != nil {
+			return err
 		}
+		a.Config = cfg
 	}
-	return "", fmt.Errorf("utron: can't find configuration file %s in %s", name, dir)
+	if !a.Model.IsOpen() {
+		if err := a.Model.OpenWithConfig(a.Config); err != nil {
+			return err
+		}
+	}
+	if err := a.loadMigrations(); err != nil {
+		return err
+	}
+	return a.Migrations.Run(args, out)
 }
 
-// AddController registers a controller, and middlewares if any is provided.
-func (a *App) AddController(ctrlfn func() controller.Controller, middlewares ...interface{}) {
-	_ = a.Router.Add(ctrlfn, middlewares...)
+// Close releases resources held by the App, it stops the background job that
+// purges expired sessions, closes the view if it implements io.Closer, and the
+// databases.
+func (a *App) Close() error {
+	var errs []error
+	if err := a.closeSessionStore(); err != nil {
+		errs = append(errs, err)
+	}
+	if c, ok := a.View.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if a.Model != nil {
+		if err := a.Model.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, db := range a.DBs {
+		if err := db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
 }
 
-// ServeHTTP serves http requests. It can be used with other http.Handler implementations.
-func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	a.Router.ServeHTTP(w, r)
+// closeSessionStore stops the background job purging expired sessions, and
+// releases the database of the session store.
+func (a *App) closeSessionStore() error {
+	if a.cleaner != nil {
+		a.cleaner.stop()
+		a.cleaner = nil
+	}
+	if a.sessionCloser == nil {
+		return nil
+	}
+	err := a.sessionCloser.Close()
+	a.sessionCloser = nil
+	return err
 }
 
 //SetNotFoundHandler this sets the hadler that is will execute when the route is
@@ -1,15 +1,19 @@
 package app
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/gernest/utron/config"
 	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/logger"
+	"github.com/gernest/utron/view"
 )
 
 const notFoundMsg = "nothing"
@@ -120,3 +124,268 @@ func TestStaticServer(t *testing.T) {
 		t.Errorf("expected %s got %s", expect, s)
 	}
 }
+
+func TestViewEngines(t *testing.T) {
+	cfg := &config.Config{ViewEngines: map[string]string{"tpl": "text", ".eml": "text"}}
+	engines, err := viewEngines(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engines[".tpl"] != view.TextEngine {
+		t.Error("expected .tpl to use the text engine")
+	}
+	if engines[".eml"] != view.TextEngine {
+		t.Error("expected .eml to use the text engine")
+	}
+	if engines[".html"] != view.HTMLEngine {
+		t.Error("expected .html to use the html engine")
+	}
+	cfg.ViewEngines["tpl"] = "bogus"
+	if _, err = viewEngines(cfg); err == nil {
+		t.Error("expected an error")
+	}
+
+	cfg.Port = 8090
+	cfg.ViewsDir = "fixtures/view"
+	cfg.NoModel = true
+	err = ValidateConfig(cfg)
+	verr, ok := err.(*config.ValidationError)
+	if !ok || len(verr.Problems) != 1 || verr.Problems[0].Key != "view_engines.tpl" {
+		t.Errorf("expected the unknown engine to be reported got %v", err)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	app := NewApp()
+	app.SetConfigPath("fixtures/embed")
+	if err := app.Init(); err == nil {
+		t.Error("expected an error when FS is not set")
+	}
+
+	app = NewApp()
+	app.SetConfigPath("fixtures/embed")
+	app.FS = fstest.MapFS{
+		"views/index.tpl": &fstest.MapFile{Data: []byte("hello {{.Name}}")},
+		"views/asset.tpl": &fstest.MapFile{Data: []byte(`{{asset "app.css"}}`)},
+		"static/app.css":  &fstest.MapFile{Data: []byte("body{}")},
+	}
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+	app.AddController(controller.GetCtrlFunc(&SimpleMVC{}))
+
+	sample := []struct {
+		path, expect string
+	}{
+		{"/simplemvc/hello", "hello gernest"},
+		{"/static/app.css", "body{}"},
+	}
+	for _, s := range sample {
+		req, _ := http.NewRequest("GET", s.path, nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if w.Body.String() != s.expect {
+			t.Errorf("%s: expected %s got %s", s.path, s.expect, w.Body.String())
+		}
+	}
+
+	// the asset function links to the fingerprinted file, which is cached
+	// for a year.
+	out := &bytes.Buffer{}
+	if err := app.View.Render(out, "asset", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "/static/app.") || out.String() == "/static/app.css" {
+		t.Fatalf("expected fingerprinted path got %s", out)
+	}
+	req, _ := http.NewRequest("GET", out.String(), nil)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Body.String() != "body{}" {
+		t.Errorf("expected body{} got %s", w.Body)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("expected immutable cache got %s", cc)
+	}
+}
+
+func TestLocales(t *testing.T) {
+	app := NewApp()
+	app.SetConfigPath("fixtures/embed")
+	app.FS = fstest.MapFS{
+		"views/index.tpl":   &fstest.MapFile{Data: []byte(`{{t .Locale "hello" .Name}}`)},
+		"locales/en.json":   &fstest.MapFile{Data: []byte(`{"hello": "hello %s"}`)},
+		"locales/fr.toml":   &fstest.MapFile{Data: []byte(`hello = "bonjour %s"`)},
+		"locales/notes.txt": &fstest.MapFile{Data: []byte("ignored")},
+	}
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+	if app.I18n == nil {
+		t.Fatal("expected the locales to be loaded")
+	}
+	app.Config.LocaleURLPrefix = true
+	app.AddController(controller.GetCtrlFunc(&SimpleMVC{}))
+
+	sample := []struct {
+		path, lang, expect string
+	}{
+		{"/simplemvc/hello", "", "hello gernest"},
+		{"/simplemvc/hello", "fr-CA,en;q=0.8", "bonjour gernest"},
+		{"/fr/simplemvc/hello", "", "bonjour gernest"},
+		{"/en/simplemvc/hello", "fr", "hello gernest"},
+	}
+	for _, s := range sample {
+		req, _ := http.NewRequest("GET", s.path, nil)
+		if s.lang != "" {
+			req.Header.Set("Accept-Language", s.lang)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if w.Body.String() != s.expect {
+			t.Errorf("%s %s: expected %s got %s", s.path, s.lang, s.expect, w.Body.String())
+		}
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	app := NewApp()
+	app.SetConfigPath("fixtures/migrate")
+	out := &bytes.Buffer{}
+	if err := app.Migrate([]string{"status"}, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "create_notes") || !strings.Contains(out.String(), "pending") {
+		t.Fatalf("expected create_notes to be pending got %s", out)
+	}
+
+	// automigrate alone does not apply versioned migrations.
+	dir := t.TempDir()
+	writeConfig(t, dir, `view_dir = "fixtures/view"
+database = "sqlite3"
+database_conn = "file:versions?mode=memory&cache=shared"
+automigrate = true
+migrations_dir = "fixtures/migrate/migrations"
+`)
+	app = NewApp()
+	app.SetConfigPath(dir)
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if app.Model.HasTable("notes") {
+		t.Error("expected the migrations not to be applied without auto_migrate_versions")
+	}
+	app.Close()
+
+	// pending migrations are applied on startup when auto_migrate_versions is
+	// set.
+	app = NewApp()
+	app.SetConfigPath("fixtures/migrate")
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+	if !app.Model.HasTable("notes") {
+		t.Error("expected the notes table to be created")
+	}
+	out.Reset()
+	if err := app.Migrate([]string{"down"}, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "rolled back 1_create_notes") {
+		t.Errorf("expected create_notes to be rolled back got %s", out)
+	}
+
+	// the configuration is validated before migrating.
+	app = NewApp()
+	app.SetConfigPath("../fixtures/invalid")
+	err := app.Migrate([]string{"status"}, out)
+	if _, ok := err.(*config.ValidationError); !ok {
+		t.Errorf("expected a validation error got %v", err)
+	}
+}
+
+type Tag struct {
+	ID   int
+	Name string
+}
+
+func TestRegisteredModels(t *testing.T) {
+	app := NewApp()
+	app.SetConfigPath("fixtures/migrate")
+	if err := app.Model.Register(&Tag{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+	if _, ok := app.Model.Registered()["Tag"]; !ok {
+		t.Fatal("expected the model registered before Init to be kept")
+	}
+	if !app.Model.HasTable(&Tag{}) {
+		t.Error("expected the tags table to be created on startup")
+	}
+}
+
+type Report struct {
+	controller.BaseController
+}
+
+func (r *Report) Index() {
+	db, ok := r.Ctx.DBs["analytics"]
+	if !ok || !db.IsOpen() {
+		r.Ctx.Set(http.StatusInternalServerError)
+		return
+	}
+	r.Ctx.Write([]byte("analytics"))
+}
+
+func TestDatabases(t *testing.T) {
+	app := NewApp()
+	app.SetConfigPath("fixtures/databases")
+	if err := app.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+	if n := app.Model.Primary().DB().Stats().MaxOpenConnections; n != 4 {
+		t.Errorf("expected 4 max open connections got %d", n)
+	}
+	app.AddController(controller.GetCtrlFunc(&Report{}))
+	req, _ := http.NewRequest("GET", "/report/index", nil)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Body.String() != "analytics" {
+		t.Errorf("expected the analytics database in the context got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestKeysGeneratedWarning(t *testing.T) {
+	for _, k := range []string{"PORT", "VIEWS_DIR", "NO_MODEL", "SESSION_KEY_PAIR", config.EnvVar} {
+		t.Setenv(k, "")
+	}
+	dir := t.TempDir()
+	base := "port = 8090\nno_model = true\nsession_store = \"cookie\"\nview_dir = \"fixtures/view\"\n"
+	for _, s := range []struct {
+		config string
+		warn   bool
+	}{
+		{base, true},
+		{base + "session_key_pair = [\"auth\", \"0123456789abcdef\"]\n", false},
+	} {
+		writeConfig(t, dir, s.config)
+		out := &bytes.Buffer{}
+		a := NewApp()
+		a.Log = logger.NewDefaultLogger(out)
+		a.SetConfigPath(dir)
+		if err := a.Init(); err != nil {
+			t.Fatal(err)
+		}
+		a.Close()
+		if strings.Contains(out.String(), "random session keys were generated") != s.warn {
+			t.Errorf("expected warning %v got %s", s.warn, out)
+		}
+	}
+}
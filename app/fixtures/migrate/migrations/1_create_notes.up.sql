@@ -0,0 +1 @@
+CREATE TABLE notes (id integer primary key, body text);
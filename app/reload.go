@@ -0,0 +1,136 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gernest/utron/config"
+)
+
+// reloader holds the configuration seen by new requests, and the functions
+// notified when it changes.
+type reloader struct {
+	mu        sync.Mutex
+	current   atomic.Value
+	listeners []func(*config.Config)
+}
+
+// CurrentConfig returns the configuration of new requests. It is Config until
+// the configuration is reloaded.
+func (a *App) CurrentConfig() *config.Config {
+	if cfg, ok := a.reload.current.Load().(*config.Config); ok {
+		return cfg
+	}
+	return a.Config
+}
+
+// OnConfigChange registers fn to be called with the new configuration after it
+// is reloaded, e.g. to change the log level or feature flags. The functions are
+// called in order, by the goroutine reloading the configuration.
+func (a *App) OnConfigChange(fn func(cfg *config.Config)) {
+	a.reload.mu.Lock()
+	a.reload.listeners = append(a.reload.listeners, fn)
+	a.reload.mu.Unlock()
+}
+
+// ReloadConfig reads the configuration files again and validates them. When
+// they are valid the new configuration replaces the current one for new
+// requests, and the OnConfigChange functions are called.
+//
+// Settings such as the port or the databases are only read when the App
+// starts. Their changes are not applied, they are returned and logged as
+// requiring a restart.
+func (a *App) ReloadConfig() (restart []string, err error) {
+	a.reload.mu.Lock()
+	next, err := loadConfig(a.ConfigPath)
+	if err == nil {
+		err = ValidateConfig(next)
+	}
+	if err != nil {
+		a.reload.mu.Unlock()
+		return nil, err
+	}
+	cfg, restart := a.CurrentConfig().Reloaded(next)
+	a.reload.current.Store(cfg)
+	listeners := append([]func(*config.Config){}, a.reload.listeners...)
+	a.reload.mu.Unlock()
+
+	if len(restart) > 0 {
+		a.Log.Warn("utron: restart to apply the changes of ", strings.Join(restart, ", "))
+	}
+	a.Log.Info("utron: configuration reloaded")
+	// the listeners are called without the lock, they may register other
+	// listeners or reload the configuration.
+	for _, fn := range listeners {
+		fn(cfg)
+	}
+	return restart, nil
+}
+
+// WatchConfig reloads the configuration when the process receives SIGHUP, and
+// when the configuration files change if interval is positive. The files are
+// polled every interval. Errors are logged, the current configuration is then
+// kept. It returns a function stopping the watch.
+//
+// Serve calls WatchConfig when Config.ReloadInterval is set.
+func (a *App) WatchConfig(interval time.Duration) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	done := make(chan struct{})
+	mods := a.configModTimes()
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+			case <-tick:
+				if a.configModTimes() == mods {
+					continue
+				}
+			}
+			if _, err := a.ReloadConfig(); err != nil {
+				a.Log.Errors("utron: reloading the configuration ", err)
+			}
+			mods = a.configModTimes()
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			if ticker != nil {
+				ticker.Stop()
+			}
+			close(done)
+		})
+	}
+}
+
+// configModTimes returns a string identifying the versions of the
+// configuration files, it changes when a file is modified.
+func (a *App) configModTimes() string {
+	cfg := a.CurrentConfig()
+	if cfg == nil {
+		return ""
+	}
+	var s strings.Builder
+	for _, file := range cfg.Files() {
+		if info, err := os.Stat(file); err == nil {
+			fmt.Fprintf(&s, "%s %v %d\n", file, info.ModTime(), info.Size())
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/logger"
+)
+
+type Settings struct {
+	controller.BaseController
+}
+
+func (s *Settings) Name() {
+	s.Ctx.Write([]byte(s.Ctx.Cfg.AppName))
+	s.String(http.StatusOK)
+}
+
+func writeConfig(t *testing.T, dir, data string) {
+	file := filepath.Join(dir, "app.toml")
+	if err := ioutil.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// make sure the modification time changes.
+	next := time.Now().Add(time.Duration(len(data)) * time.Second)
+	if err := os.Chtimes(file, next, next); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newReloadApp(t *testing.T) (*App, string) {
+	for _, k := range []string{"APP_NAME", "PORT", "VIEWS_DIR", "NO_MODEL", config.EnvVar} {
+		t.Setenv(k, "")
+	}
+	dir, err := ioutil.TempDir("", "utron-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	writeConfig(t, dir, `app_name = "first"
+port = 8090
+no_model = true
+session_store = "cookie"
+view_dir = "fixtures/view"
+`)
+	a := NewApp()
+	a.Log = logger.NewDefaultLogger(ioutil.Discard)
+	a.SetConfigPath(dir)
+	if err = a.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	a.AddController(controller.GetCtrlFunc(&Settings{}))
+	return a, dir
+}
+
+func TestReloadConfig(t *testing.T) {
+	a, dir := newReloadApp(t)
+	var changed *config.Config
+	a.OnConfigChange(func(cfg *config.Config) { changed = cfg })
+
+	writeConfig(t, dir, `app_name = "second"
+port = 9000
+no_model = true
+session_store = "cookie"
+view_dir = "fixtures/view"
+`)
+	restart, err := a.ReloadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restart, []string{"port"}) {
+		t.Errorf("expected the port to require a restart got %v", restart)
+	}
+	cfg := a.CurrentConfig()
+	if changed != cfg || cfg.AppName != "second" || cfg.Port != 8090 {
+		t.Errorf("expected the live settings only to change got %s %d", cfg.AppName, cfg.Port)
+	}
+	if a.Config.AppName != "first" {
+		t.Errorf("expected Config to be unchanged got %s", a.Config.AppName)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("GET", "/settings/name", nil))
+	if w.Body.String() != "second" {
+		t.Errorf("expected requests to see the new config got %s", w.Body)
+	}
+
+	// invalid configurations are not applied.
+	writeConfig(t, dir, `app_name = "third"
+port = 0
+`)
+	if _, err = a.ReloadConfig(); err == nil {
+		t.Error("expected an error")
+	}
+	if a.CurrentConfig() != cfg {
+		t.Error("expected the configuration to be kept")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	a, dir := newReloadApp(t)
+	changes := make(chan string, 2)
+	a.OnConfigChange(func(cfg *config.Config) { changes <- cfg.AppName })
+	stop := a.WatchConfig(10 * time.Millisecond)
+	defer stop()
+
+	writeConfig(t, dir, `app_name = "polled"
+port = 8090
+no_model = true
+session_store = "cookie"
+view_dir = "fixtures/view"
+`)
+	select {
+	case name := <-changes:
+		if name != "polled" {
+			t.Errorf("expected polled got %s", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the change to be detected")
+	}
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Signal(syscall.SIGHUP); err != nil {
+		t.Skip(err)
+	}
+	select {
+	case name := <-changes:
+		if name != "polled" {
+			t.Errorf("expected polled got %s", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a reload on SIGHUP")
+	}
+}
+
+func TestReloadConfigListeners(t *testing.T) {
+	a, _ := newReloadApp(t)
+	done := make(chan int, 1)
+	var calls int
+	a.OnConfigChange(func(*config.Config) {
+		calls++
+		if calls == 1 {
+			a.OnConfigChange(func(*config.Config) {})
+			if _, err := a.ReloadConfig(); err != nil {
+				t.Error(err)
+			}
+			done <- calls
+		}
+	})
+	go func() {
+		if _, err := a.ReloadConfig(); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case n := <-done:
+		if n != 2 {
+			t.Errorf("expected the listener to be called again got %d calls", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected listeners to reload the configuration without a deadlock")
+	}
+}
+
+func TestReloadLogLevel(t *testing.T) {
+	a, dir := newReloadApp(t)
+	buf := &bytes.Buffer{}
+	a.Log = logger.NewDefaultLogger(buf)
+	base := "port = 8090\nno_model = true\nsession_store = \"cookie\"\nview_dir = \"fixtures/view\"\n"
+	writeConfig(t, dir, base+"log_level = \"warn\"\n")
+	a.Config, _ = loadConfig(dir)
+	if err := a.configureLog(); err != nil {
+		t.Fatal(err)
+	}
+	a.Log.Info("hidden")
+	writeConfig(t, dir, base+"log_level = \"debug\"\n")
+	if _, err := a.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	a.Log.(logger.Leveled).Debug("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), ">>DEBUG>> shown") {
+		t.Errorf("expected the level to follow the config got %s", buf)
+	}
+
+	// configuring the log again does not add another hook.
+	if err := a.configureLog(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(a.reload.listeners); n != 1 {
+		t.Errorf("expected 1 config change hook got %d", n)
+	}
+
+	// without log_level the level is the default again.
+	buf.Reset()
+	writeConfig(t, dir, base)
+	if _, err := a.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	a.Log.(logger.Leveled).Debug("hidden")
+	a.Log.Info("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Errorf("expected the info level got %s", buf)
+	}
+}
@@ -0,0 +1,252 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is the time given to requests in flight to complete
+// when the server stops and Config.ShutdownTimeout is not set.
+const defaultShutdownTimeout = 30 * time.Second
+
+// server holds the http server of the App and its lifecycle hooks.
+type server struct {
+	once       sync.Once
+	srv        *http.Server
+	redirect   *http.Server
+	onStart    []func() error
+	onShutdown []func(context.Context) error
+
+	stop    sync.Once
+	stopErr error
+	stopped chan struct{}
+}
+
+// OnStart registers fn to be called by Run before the server accepts
+// requests, e.g. to warm caches. Run fails when fn returns an error.
+func (a *App) OnStart(fn func() error) {
+	a.server.onStart = append(a.server.onStart, fn)
+}
+
+// OnShutdown registers fn to be called by Shutdown after the requests in flight
+// have completed, and before the databases are closed. The functions are called
+// in reverse order, ctx is the context passed to Shutdown.
+func (a *App) OnShutdown(fn func(ctx context.Context) error) {
+	a.server.onShutdown = append(a.server.onShutdown, fn)
+}
+
+// Server returns the http.Server used by Run. It serves the App, with the
+// timeouts set in the Config. It can be customised before Run is called, e.g.
+// to set an ErrorLog.
+func (a *App) Server() *http.Server {
+	a.server.once.Do(func() {
+		srv := &http.Server{Handler: a}
+		if cfg := a.Config; cfg != nil {
+			srv.Addr = fmt.Sprintf(":%d", cfg.ServerPort())
+			srv.ReadTimeout = seconds(cfg.ReadTimeout)
+			srv.ReadHeaderTimeout = seconds(cfg.ReadHeaderTimeout)
+			srv.WriteTimeout = seconds(cfg.WriteTimeout)
+			srv.IdleTimeout = seconds(cfg.IdleTimeout)
+		}
+		a.server.srv = srv
+		a.server.stopped = make(chan struct{})
+	})
+	return a.server.srv
+}
+
+// Listen returns a listener on Config.Socket when it is set, or on
+// Config.Port, 8090 by default. A stale socket file left by a previous run is removed, other
+// files and the sockets of running servers are not.
+func (a *App) Listen() (net.Listener, error) {
+	if a.Config == nil {
+		return nil, errors.New("utron: the App has no configuration, call Init first")
+	}
+	if a.Config.Socket != "" {
+		if err := removeStaleSocket(a.Config.Socket); err != nil {
+			return nil, err
+		}
+		return net.Listen("unix", a.Config.Socket)
+	}
+	return net.Listen("tcp", a.Server().Addr)
+}
+
+// removeStaleSocket removes the socket file path when no server listens on it.
+// It fails when path is not a socket, or when the socket is in use.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("utron: %s exists and is not a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("utron: listen unix %s: address already in use", path)
+	}
+	return os.Remove(path)
+}
+
+// Run initializes the App if needed, and serves it on the listener returned by
+// Listen until the process receives SIGINT or SIGTERM, or Shutdown is called.
+// The requests in flight are then given Config.ShutdownTimeout seconds to
+// complete before the App is closed.
+//
+// Run returns nil when the server was shut down gracefully.
+func (a *App) Run() error {
+	if !a.isInit {
+		if err := a.Init(); err != nil {
+			return err
+		}
+	}
+	l, err := a.Listen()
+	if err != nil {
+		return err
+	}
+	return a.Serve(l)
+}
+
+// Serve is like Run, using the listener l. It allows listeners made by the
+// caller, e.g. by socket activation.
+//
+// The App is served over HTTPS when Config.TLSCert is set, and plain HTTP
+// requests to Config.HTTPRedirectPort are then redirected to HTTPS.
+func (a *App) Serve(l net.Listener) error {
+	srv := a.Server()
+	if useTLS(a.Config) {
+		c, err := tlsConfig(a.Config)
+		if err != nil {
+			_ = l.Close()
+			return err
+		}
+		srv.TLSConfig = c
+		if a.Config.DisableHTTP2 {
+			srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+		}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	if a.Config != nil && a.Config.ReloadInterval != 0 {
+		defer a.WatchConfig(seconds(a.Config.ReloadInterval))()
+	}
+
+	for _, fn := range a.server.onStart {
+		if err := fn(); err != nil {
+			_ = l.Close()
+			_ = a.Shutdown(context.Background())
+			return err
+		}
+	}
+	if srv.TLSConfig != nil && a.Config.HTTPRedirectPort > 0 {
+		if err := a.serveRedirect(); err != nil {
+			_ = l.Close()
+			_ = a.Shutdown(context.Background())
+			return err
+		}
+	}
+	a.Log.Info("utron: listening on ", l.Addr())
+	errc := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			errc <- srv.ServeTLS(l, "", "")
+			return
+		}
+		errc <- srv.Serve(l)
+	}()
+	select {
+	case err := <-errc:
+		if err == http.ErrServerClosed {
+			// Shutdown was called, it closes the App.
+			<-a.server.stopped
+			return nil
+		}
+		_ = a.Shutdown(context.Background())
+		return err
+	case sig := <-signals:
+		a.Log.Info("utron: received ", sig, ", shutting down")
+		timeout := defaultShutdownTimeout
+		if a.Config != nil && a.Config.ShutdownTimeout > 0 {
+			timeout = seconds(a.Config.ShutdownTimeout)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return a.Shutdown(ctx)
+	}
+}
+
+// Shutdown stops the server gracefully. It stops accepting connections, waits
+// for the requests in flight to complete or for ctx to be done, calls the
+// OnShutdown functions and closes the App. Only the first call has an effect,
+// the others return the same error.
+func (a *App) Shutdown(ctx context.Context) error {
+	srv := a.Server()
+	a.server.stop.Do(func() {
+		defer close(a.server.stopped)
+		var errs []error
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		if a.server.redirect != nil {
+			if err := a.server.redirect.Shutdown(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		hooks := a.server.onShutdown
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := hooks[i](ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := a.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		for _, err := range errs {
+			a.Log.Errors(err)
+		}
+		if len(errs) > 0 {
+			a.server.stopErr = errs[0]
+		}
+	})
+	<-a.server.stopped
+	return a.server.stopErr
+}
+
+// serveRedirect listens on Config.HTTPRedirectPort, and redirects the requests
+// to HTTPS.
+func (a *App) serveRedirect() error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.Config.HTTPRedirectPort))
+	if err != nil {
+		return err
+	}
+	a.server.redirect = &http.Server{
+		Handler:           redirectHandler(a.Config.ServerPort()),
+		ReadTimeout:       seconds(a.Config.ReadTimeout),
+		ReadHeaderTimeout: seconds(a.Config.ReadHeaderTimeout),
+		WriteTimeout:      seconds(a.Config.WriteTimeout),
+		IdleTimeout:       seconds(a.Config.IdleTimeout),
+	}
+	go func() {
+		if err := a.server.redirect.Serve(l); err != nil && err != http.ErrServerClosed {
+			a.Log.Errors(err)
+		}
+	}()
+	a.Log.Info("utron: redirecting to https from ", l.Addr())
+	return nil
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
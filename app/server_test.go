@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/logger"
+)
+
+type Slow struct {
+	controller.BaseController
+	started chan struct{}
+}
+
+func (s *Slow) Wait() {
+	close(s.started)
+	time.Sleep(200 * time.Millisecond)
+	s.Ctx.Write([]byte("done"))
+	s.String(http.StatusOK)
+}
+
+func newServerApp(cfg *config.Config) *App {
+	a := NewApp()
+	a.Log = logger.NewDefaultLogger(ioutil.Discard)
+	a.Config = cfg
+	return a
+}
+
+func TestShutdown(t *testing.T) {
+	a := newServerApp(&config.Config{ReadTimeout: 5, IdleTimeout: 7})
+	if srv := a.Server(); srv.ReadTimeout != 5*time.Second || srv.IdleTimeout != 7*time.Second {
+		t.Errorf("expected the timeouts of the config got %v %v", srv.ReadTimeout, srv.IdleTimeout)
+	}
+	slow := &Slow{started: make(chan struct{})}
+	a.AddController(controller.GetCtrlFunc(slow))
+	var calls []string
+	a.OnStart(func() error {
+		calls = append(calls, "start")
+		return nil
+	})
+	a.OnShutdown(func(context.Context) error {
+		calls = append(calls, "first")
+		return nil
+	})
+	a.OnShutdown(func(context.Context) error {
+		calls = append(calls, "second")
+		return nil
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- a.Serve(l) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String() + "/slow/wait")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		done <- result{string(b), err}
+	}()
+	<-slow.started
+	if err = a.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the request in flight completes.
+	if r := <-done; r.err != nil || r.body != "done" {
+		t.Errorf("expected the request to complete got %q %v", r.body, r.err)
+	}
+	if err = <-served; err != nil {
+		t.Errorf("expected a graceful shutdown got %v", err)
+	}
+	if len(calls) != 3 || calls[0] != "start" || calls[1] != "second" || calls[2] != "first" {
+		t.Errorf("unexpected hook calls %v", calls)
+	}
+	if _, err = http.Get("http://" + l.Addr().String() + "/slow/wait"); err == nil {
+		t.Error("expected the server to be stopped")
+	}
+}
+
+func TestRunSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utron-socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "app.sock")
+
+	// a stale socket file is replaced.
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	a := newServerApp(&config.Config{Socket: socket, ShutdownTimeout: 1})
+	a.isInit = true
+	started := make(chan struct{})
+	a.OnStart(func() error {
+		close(started)
+		return nil
+	})
+	stopped := false
+	a.OnShutdown(func(context.Context) error {
+		stopped = true
+		return nil
+	})
+	ran := make(chan error, 1)
+	go func() { ran <- a.Run() }()
+	<-started
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", socket)
+		},
+	}}
+	res, err := client.Get("http://app/nothing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, res.StatusCode)
+	}
+
+	// the server stops gracefully on SIGTERM.
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Signal(syscall.SIGTERM); err != nil {
+		t.Skip(err)
+	}
+	select {
+	case err = <-ran:
+		if err != nil {
+			t.Errorf("expected a graceful shutdown got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the server to stop")
+	}
+	if !stopped {
+		t.Error("expected the shutdown hooks to be called")
+	}
+}
+
+func TestListenSocketInUse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utron-socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a regular file is not removed.
+	file := filepath.Join(dir, "app.conf")
+	if err = ioutil.WriteFile(file, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a := newServerApp(&config.Config{Socket: file})
+	if _, err = a.Listen(); err == nil {
+		t.Error("expected an error listening on a regular file")
+	}
+	if b, err := ioutil.ReadFile(file); err != nil || string(b) != "keep" {
+		t.Errorf("expected the file to be kept got %q %v", b, err)
+	}
+
+	// the socket of a running server is not removed.
+	socket := filepath.Join(dir, "app.sock")
+	live, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer live.Close()
+	a = newServerApp(&config.Config{Socket: socket})
+	if _, err = a.Listen(); err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Errorf("expected address already in use got %v", err)
+	}
+	go func() {
+		if conn, err := live.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("expected the running server to keep its socket got %v", err)
+	}
+	conn.Close()
+}
+
+func TestRunStartFailure(t *testing.T) {
+	a := newServerApp(&config.Config{})
+	a.isInit = true
+	fail := errors.New("not ready")
+	a.OnStart(func() error { return fail })
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Serve(l); err != fail {
+		t.Errorf("expected %v got %v", fail, err)
+	}
+}
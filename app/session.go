@@ -0,0 +1,235 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gernest/qlstore"
+	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/logger"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// defaultSessionMaxAge is used by the server side stores when the
+	// configuration does not specify SessionMaxAge, they need a positive age to
+	// compute when a session expires. Cookies of the cookie store are then
+	// browser session cookies.
+	defaultSessionMaxAge = 2592000
+
+	// defaultCleanupInterval is the number of seconds between expired session
+	// purges when SessionCleanupInterval is not set.
+	defaultCleanupInterval = 300
+)
+
+// purgeFunc removes expired sessions from a server side store.
+type purgeFunc func() error
+
+// sessionOptions returns cookie options built from the session fields of cfg.
+func sessionOptions(cfg *config.Config) (*sessions.Options, error) {
+	sameSite, err := getSameSite(cfg.SessionSameSite)
+	if err != nil {
+		return nil, err
+	}
+	opts := &sessions.Options{
+		Path:     cfg.SessionPath,
+		Domain:   cfg.SessionDomain,
+		MaxAge:   cfg.SessionMaxAge,
+		Secure:   cfg.SessionSecure,
+		HttpOnly: cfg.SessionHTTPOnly,
+		SameSite: sameSite,
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	return opts, nil
+}
+
+// getSameSite maps the SessionSameSite config value to http.SameSite.
+func getSameSite(v string) (http.SameSite, error) {
+	switch strings.ToLower(v) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("utron: unknown session_same_site value %q", v)
+}
+
+// getSesionStore returns the session store named by cfg.SessionStore, with
+// all session settings from cfg applied. For server side stores the returned
+// purgeFunc deletes expired sessions, it is nil for the cookie store. The
+// io.Closer releases the database of the ql store, it is nil for the other
+// stores.
+func getSesionStore(cfg *config.Config) (sessions.Store, purgeFunc, io.Closer, error) {
+	opts, err := sessionOptions(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keys, err := keyPairs(cfg.SessionKeyPair)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if cfg.SessionStore == "cookie" {
+		store := sessions.NewCookieStore(keys...)
+		if opts.MaxAge != 0 {
+			store.MaxAge(opts.MaxAge)
+		}
+		store.Options = opts
+		return store, nil, nil, nil
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = defaultSessionMaxAge
+	}
+	switch cfg.SessionStore {
+	case "file":
+		dir := cfg.SessionDir
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		store := sessions.NewFilesystemStore(dir, keys...)
+		store.Options = opts
+		store.MaxAge(opts.MaxAge)
+		return store, purgeFileSessions(dir, opts.MaxAge), nil, nil
+	case "", "ql":
+		db, err := sql.Open("ql-mem", "session.db")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		err = qlstore.Migrate(db)
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, nil, err
+		}
+		store := qlstore.NewQLStore(db, opts.Path, opts.MaxAge, keys...)
+		store.Options = opts
+		store.MaxAge(opts.MaxAge)
+		return store, purgeQLSessions(db), db, nil
+	}
+	return nil, nil, nil, fmt.Errorf("utron: unknown session store %q", cfg.SessionStore)
+}
+
+// purgeQLSessions deletes sessions whose expiry time has passed from the ql
+// session table.
+func purgeQLSessions(db *sql.DB) purgeFunc {
+	return func() error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM sessions WHERE expires_on < now();`)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+}
+
+// purgeFileSessions deletes session files in dir that were not modified for
+// longer than maxAge seconds.
+func purgeFileSessions(dir string, maxAge int) purgeFunc {
+	return func() error {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		deadline := time.Now().Add(-time.Duration(maxAge) * time.Second)
+		for _, f := range files {
+			if f.IsDir() || !strings.HasPrefix(f.Name(), "session_") {
+				continue
+			}
+			if f.ModTime().Before(deadline) {
+				if err := os.Remove(filepath.Join(dir, f.Name())); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// sessionCleaner runs purge periodically in the background until stopped.
+type sessionCleaner struct {
+	purge    purgeFunc
+	interval time.Duration
+	log      logger.Logger
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+func newSessionCleaner(purge purgeFunc, interval time.Duration, log logger.Logger) *sessionCleaner {
+	return &sessionCleaner{
+		purge:    purge,
+		interval: interval,
+		log:      log,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *sessionCleaner) start() {
+	go s.run()
+}
+
+func (s *sessionCleaner) run() {
+	defer close(s.done)
+	tick := time.NewTicker(s.interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			if err := s.purge(); err != nil && s.log != nil {
+				s.log.Errors("utron: purging expired sessions ", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// stop signals the cleaner to exit and waits for the running purge, if any,
+// to finish.
+func (s *sessionCleaner) stop() {
+	close(s.quit)
+	<-s.done
+}
+
+// keyPairs converts the SessionKeyPair config values into securecookie key
+// pairs. Keys are read in pairs of authentication and encryption keys, the
+// first pair is used to encode cookies and all pairs are tried when decoding.
+// This allows keys to be rotated by prepending a new pair and keeping the old
+// ones until existing sessions expire.
+func keyPairs(src []string) ([][]byte, error) {
+	var pairs [][]byte
+	for k, v := range src {
+		if k%2 == 0 {
+			if v == "" {
+				return nil, fmt.Errorf("utron: session authentication key %d is empty", k)
+			}
+		} else {
+			switch len(v) {
+			case 0, 16, 24, 32:
+			default:
+				return nil, fmt.Errorf("utron: session encryption key %d must be 16, 24 or 32 bytes long", k)
+			}
+		}
+		var key []byte
+		if v != "" {
+			key = []byte(v)
+		}
+		pairs = append(pairs, key)
+	}
+	return pairs, nil
+}
@@ -0,0 +1,315 @@
+package app
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gernest/qlstore"
+	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/logger"
+)
+
+func TestSessionOptions(t *testing.T) {
+	cfg := &config.Config{
+		SessionPath:     "/app",
+		SessionDomain:   "example.com",
+		SessionMaxAge:   60,
+		SessionHTTPOnly: true,
+		SessionSameSite: "Strict",
+	}
+	opts, err := sessionOptions(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Path != cfg.SessionPath {
+		t.Errorf("expected %s got %s", cfg.SessionPath, opts.Path)
+	}
+	if opts.Domain != cfg.SessionDomain {
+		t.Errorf("expected %s got %s", cfg.SessionDomain, opts.Domain)
+	}
+	if opts.MaxAge != cfg.SessionMaxAge {
+		t.Errorf("expected %d got %d", cfg.SessionMaxAge, opts.MaxAge)
+	}
+	if opts.Secure {
+		t.Error("expected secure to be false")
+	}
+	if !opts.HttpOnly {
+		t.Error("expected httponly to be true")
+	}
+	if opts.SameSite != http.SameSiteStrictMode {
+		t.Errorf("expected %v got %v", http.SameSiteStrictMode, opts.SameSite)
+	}
+
+	// defaults
+	opts, err = sessionOptions(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Path != "/" {
+		t.Errorf("expected / got %s", opts.Path)
+	}
+	if opts.MaxAge != 0 {
+		t.Errorf("expected 0 got %d", opts.MaxAge)
+	}
+
+	_, err = sessionOptions(&config.Config{SessionSameSite: "bogus"})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestGetSessionStore(t *testing.T) {
+	cfg := config.DefaultConfig()
+	for _, name := range []string{"", "ql", "cookie", "file"} {
+		cfg.SessionStore = name
+		store, _, closer, err := getSesionStore(cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if (closer != nil) != (name == "" || name == "ql") {
+			t.Errorf("%s: unexpected closer %T", name, closer)
+		}
+		if closer != nil {
+			defer closer.Close()
+		}
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, err := store.New(req, cfg.SessionName)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		s.Values["name"] = "gernest"
+		w := httptest.NewRecorder()
+		if err = s.Save(req, w); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("%s: expected 1 cookie got %d", name, len(cookies))
+		}
+		if cookies[0].Name != cfg.SessionName {
+			t.Errorf("%s: expected %s got %s", name, cfg.SessionName, cookies[0].Name)
+		}
+		if !cookies[0].HttpOnly {
+			t.Errorf("%s: expected httponly cookie", name)
+		}
+		if cookies[0].Secure {
+			t.Errorf("%s: expected insecure cookie", name)
+		}
+	}
+
+	// without max age, cookies are browser session cookies and server side
+	// sessions expire after the default age.
+	cfg.SessionMaxAge = 0
+	for _, name := range []string{"cookie", "file"} {
+		cfg.SessionStore = name
+		store, _, _, err := getSesionStore(cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		req, _ := http.NewRequest("GET", "/", nil)
+		s, err := store.New(req, cfg.SessionName)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		w := httptest.NewRecorder()
+		if err = s.Save(req, w); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		maxAge := w.Result().Cookies()[0].MaxAge
+		if name == "cookie" && maxAge != 0 {
+			t.Errorf("expected a browser session cookie got max age %d", maxAge)
+		}
+		if name == "file" && maxAge != defaultSessionMaxAge {
+			t.Errorf("expected max age %d got %d", defaultSessionMaxAge, maxAge)
+		}
+	}
+
+	cfg.SessionStore = "bogus"
+	_, _, _, err := getSesionStore(cfg)
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestPurgeFileSessions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utron-sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := config.DefaultConfig()
+	cfg.SessionStore = "file"
+	cfg.SessionDir = dir
+	store, purge, _, err := getSesionStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, cfg.SessionName)
+	if err = s.Save(req, httptest.NewRecorder()); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "session_"+s.ID)
+	if err = purge(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(file); err != nil {
+		t.Fatal("expected fresh session to survive purge ", err)
+	}
+
+	old := time.Now().Add(-time.Duration(cfg.SessionMaxAge+1) * time.Second)
+	if err = os.Chtimes(file, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err = purge(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected expired session to be removed got %v", err)
+	}
+}
+
+func TestSessionCleaner(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	c := newSessionCleaner(func() error {
+		calls <- struct{}{}
+		return nil
+	}, time.Millisecond, nil)
+	c.start()
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Error("expected purge to be called")
+	}
+	c.stop()
+}
+
+func TestInitStopsSessionCleaner(t *testing.T) {
+	for _, k := range []string{"PORT", "VIEWS_DIR", "NO_MODEL", "SESSION_STORE", config.EnvVar} {
+		t.Setenv(k, "")
+	}
+	dir := t.TempDir()
+	writeConfig(t, dir, "no_model = true\nsession_store = \"ql\"\nview_dir = \"fixtures/view\"\n")
+	a := NewApp()
+	a.Log = logger.NewDefaultLogger(ioutil.Discard)
+	a.SetConfigPath(dir)
+	if err := a.Init(); err != nil {
+		t.Fatal(err)
+	}
+	first := a.cleaner
+	if first == nil {
+		t.Fatal("expected a session cleaner")
+	}
+	firstDB, ok := a.sessionCloser.(*sql.DB)
+	if !ok {
+		t.Fatalf("expected the database of the ql store got %T", a.sessionCloser)
+	}
+	if err := a.Init(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-first.done:
+	default:
+		t.Error("expected the previous cleaner to be stopped")
+	}
+	if a.cleaner == first {
+		t.Error("expected a new cleaner")
+	}
+	if err := firstDB.Ping(); err == nil {
+		t.Error("expected the previous session database to be closed")
+	}
+
+	// the new store still works.
+	req, _ := http.NewRequest("GET", "/", nil)
+	sess, _ := a.SessionStore.New(req, a.Config.SessionName)
+	if err := sess.Save(req, httptest.NewRecorder()); err != nil {
+		t.Fatal(err)
+	}
+	db := a.sessionCloser.(*sql.DB)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err == nil {
+		t.Error("expected the session database to be closed")
+	}
+	if a.cleaner != nil || a.sessionCloser != nil {
+		t.Error("expected the session store to be released")
+	}
+}
+
+func TestPurgeQLSessions(t *testing.T) {
+	db, err := sql.Open("ql-mem", "purge.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err = qlstore.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	tx, _ := db.Begin()
+	_, err = tx.Exec(`INSERT INTO sessions (key, expires_on) VALUES ($1, $2), ($3, $4);`,
+		"old", time.Now().Add(-time.Hour), "fresh", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = purgeQLSessions(db)(); err != nil {
+		t.Fatal(err)
+	}
+	var key string
+	if err = db.QueryRow(`SELECT key FROM sessions;`).Scan(&key); err != nil {
+		t.Fatal(err)
+	}
+	if key != "fresh" {
+		t.Errorf("expected fresh got %s", key)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKeys := []string{"ePAPW9vJv7gHoftvQTyNj5VkWB52mlza", "N8SmpJ00aSpepNrKoyYxmAJhwVuKEWZD"}
+	newKeys := []string{"JNIRXzKPRGfvZbNyLfBvTbjGRRpivNbS", "LXDRkwMyuTgmWjzOoVaZBMqiqEQhFTWm"}
+
+	cfg := config.DefaultConfig()
+	cfg.SessionStore = "cookie"
+	cfg.SessionKeyPair = oldKeys
+	store, _, _, err := getSesionStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	s, _ := store.New(req, cfg.SessionName)
+	s.Values["name"] = "gernest"
+	w := httptest.NewRecorder()
+	if err = s.Save(req, w); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.SessionKeyPair = append(newKeys, oldKeys...)
+	store, _, _, err = getSesionStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.AddCookie(w.Result().Cookies()[0])
+	s, err = store.New(req, cfg.SessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Values["name"] != "gernest" {
+		t.Errorf("expected session encoded with old keys to decode, got %v", s.Values)
+	}
+
+	_, err = keyPairs([]string{"hash", "short"})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gernest/utron/config"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// useTLS returns true if the App is served over HTTPS.
+func useTLS(cfg *config.Config) bool {
+	return cfg != nil && (cfg.TLSCert != "" || cfg.TLSKey != "")
+}
+
+// tlsConfig returns the TLS settings of cfg, with the certificate loaded.
+func tlsConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.TLSCert == "" || cfg.TLSKey == "" {
+		return nil, errors.New("utron: tls_cert and tls_key must both be set")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("utron: loading the TLS certificate %v", err)
+	}
+	c := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.TLSMinVersion != "" {
+		v, ok := tlsVersions[cfg.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("utron: unknown tls_min_version %q, supported are 1.0, 1.1, 1.2 and 1.3", cfg.TLSMinVersion)
+		}
+		c.MinVersion = v
+	}
+	if cfg.TLSClientCA != "" {
+		data, err := ioutil.ReadFile(cfg.TLSClientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("utron: no certificates found in %s", cfg.TLSClientCA)
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if cfg.TLSClientAuth != "" {
+		auth, ok := clientAuthTypes[cfg.TLSClientAuth]
+		if !ok {
+			return nil, fmt.Errorf("utron: unknown tls_client_auth %q", cfg.TLSClientAuth)
+		}
+		if auth >= tls.VerifyClientCertIfGiven && c.ClientCAs == nil {
+			return nil, fmt.Errorf("utron: tls_client_auth %s needs tls_client_ca", cfg.TLSClientAuth)
+		}
+		c.ClientAuth = auth
+	}
+	return c, nil
+}
+
+// setHSTS sets the Strict-Transport-Security header on responses to HTTPS
+// requests, when Config.HSTSMaxAge is set.
+func setHSTS(cfg *config.Config, w http.ResponseWriter, r *http.Request) {
+	if cfg == nil || cfg.HSTSMaxAge <= 0 || r.TLS == nil {
+		return
+	}
+	v := "max-age=" + strconv.Itoa(cfg.HSTSMaxAge)
+	if cfg.HSTSIncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	w.Header().Set("Strict-Transport-Security", v)
+}
+
+// redirectHandler redirects requests to the same URL over HTTPS on port.
+func redirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != 0 && port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
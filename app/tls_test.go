@@ -0,0 +1,234 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gernest/utron/config"
+)
+
+// testCert is a certificate and its key, signed by parent or self signed.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, ca bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if ca {
+		tpl.IsCA = true
+		tpl.BasicConstraintsValid = true
+	}
+	signer, signerKey := tpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write writes the certificate and the key as PEM files in dir.
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utron-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, "ca", nil, true)
+	caFile, _ := ca.write(t, dir, "ca")
+	cert, key := newTestCert(t, "server", ca, false).write(t, dir, "server")
+
+	c, err := tlsConfig(&config.Config{TLSCert: cert, TLSKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MinVersion != tls.VersionTLS12 || c.ClientAuth != tls.NoClientCert {
+		t.Errorf("expected TLS 1.2 without client certificates got %+v", c)
+	}
+	c, err = tlsConfig(&config.Config{TLSCert: cert, TLSKey: key, TLSMinVersion: "1.3", TLSClientCA: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MinVersion != tls.VersionTLS13 || c.ClientAuth != tls.RequireAndVerifyClientCert || c.ClientCAs == nil {
+		t.Errorf("expected TLS 1.3 with verified client certificates got %+v", c)
+	}
+
+	invalid := []*config.Config{
+		{TLSCert: cert},
+		{TLSCert: cert, TLSKey: caFile},
+		{TLSCert: cert, TLSKey: key, TLSMinVersion: "1.4"},
+		{TLSCert: cert, TLSKey: key, TLSClientAuth: "always"},
+		{TLSCert: cert, TLSKey: key, TLSClientAuth: "require_and_verify"},
+		{TLSCert: cert, TLSKey: key, TLSClientCA: key},
+	}
+	for _, cfg := range invalid {
+		if _, err = tlsConfig(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utron-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, "ca", nil, true)
+	caFile, _ := ca.write(t, dir, "ca")
+	cert, key := newTestCert(t, "server", ca, false).write(t, dir, "server")
+	client := newTestCert(t, "client", ca, false)
+
+	a := newServerApp(&config.Config{
+		TLSCert:     cert,
+		TLSKey:      key,
+		TLSClientCA: caFile,
+		HSTSMaxAge:  600,
+	})
+	a.isInit = true
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- a.Serve(l) }()
+	defer func() {
+		_ = a.Shutdown(context.Background())
+		if err := <-served; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		c := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+			ForceAttemptHTTP2: true,
+		}}
+		return c.Get("https://" + l.Addr().String() + "/")
+	}
+
+	if _, err = get(); err == nil {
+		t.Error("expected clients without certificates to be rejected")
+	}
+	res, err := get(client.tls())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2 got %s", res.Proto)
+	}
+	if h := res.Header.Get("Strict-Transport-Security"); h != "max-age=600" {
+		t.Errorf("expected the HSTS header got %q", h)
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	sample := []struct {
+		host     string
+		port     int
+		location string
+	}{
+		{"example.com:8080", 8443, "https://example.com:8443/a?b=c"},
+		{"example.com", 443, "https://example.com/a?b=c"},
+	}
+	for _, s := range sample {
+		req := httptest.NewRequest("GET", "http://"+s.host+"/a?b=c", nil)
+		w := httptest.NewRecorder()
+		redirectHandler(s.port).ServeHTTP(w, req)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != s.location {
+			t.Errorf("expected redirect to %s got %d %s", s.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	// without a port the redirect goes to the default port the server
+	// listens on.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	a := newServerApp(&config.Config{HTTPRedirectPort: l.Addr().(*net.TCPAddr).Port})
+	if err = a.serveRedirect(); err != nil {
+		t.Fatal(err)
+	}
+	defer a.server.redirect.Close()
+	c := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := c.Get("http://" + addr + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if loc := res.Header.Get("Location"); loc != "https://127.0.0.1:8090/a" {
+		t.Errorf("expected a redirect to the default port got %s", loc)
+	}
+
+	// HSTS is only sent over HTTPS.
+	w := httptest.NewRecorder()
+	setHSTS(&config.Config{HSTSMaxAge: 60}, w, httptest.NewRequest("GET", "/", nil))
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("expected no HSTS header over HTTP")
+	}
+}
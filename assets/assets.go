@@ -0,0 +1,302 @@
+// Package assets serves static files with cache busting. Every file gets a
+// fingerprinted name containing a hash of its content, e.g. app.css is also
+// served as app.3f9a2c1d.css. Fingerprinted names never change content, so they
+// are served with far future cache headers, and templates link to them with
+// the asset function.
+//
+// The files are read, hashed and compressed once by New. Files added later are
+// still served by their plain name, but they have no fingerprinted name and are
+// not compressed until the Manager is created again.
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// immutable is the Cache-Control header of fingerprinted files.
+	immutable = "public, max-age=31536000, immutable"
+
+	// revalidate is the Cache-Control header of files requested by their
+	// plain name.
+	revalidate = "public, max-age=0, must-revalidate"
+
+	// hashLen is the number of hex characters of the content hash used in
+	// fingerprinted names.
+	hashLen = 8
+)
+
+// compressible are the content types which are gzipped when no precompressed
+// .gz file is available.
+var compressible = []string{
+	"text/", "application/javascript", "application/json", "application/xml", "image/svg+xml",
+}
+
+// Options are settings for the Manager.
+type Options struct {
+	// Dev disables fingerprinting and caching, so changes to the files are seen
+	// without restarting.
+	Dev bool
+}
+
+// Manager serves the files of a file system.
+type Manager struct {
+	fsys   fs.FS
+	prefix string
+	opts   Options
+	files  map[string]*file
+	hashed map[string]*file
+}
+
+// file is a static file and its precompressed variants.
+type file struct {
+	name    string
+	hashed  string
+	hash    string
+	modTime time.Time
+	gzip    []byte
+	gzFile  string
+	brFile  string
+}
+
+// New returns a Manager serving the files in fsys. prefix is the URL path the
+// Manager is mounted on, e.g. /static/, it is used to build the paths returned
+// by Path.
+//
+// Files ending with .gz and .br are precompressed variants of the file without
+// the extension, they are served to clients accepting the encoding.
+func New(fsys fs.FS, prefix string, opts Options) (*Manager, error) {
+	m := &Manager{
+		fsys:   fsys,
+		prefix: "/" + strings.Trim(prefix, "/") + "/",
+		opts:   opts,
+		files:  make(map[string]*file),
+		hashed: make(map[string]*file),
+	}
+	if m.prefix == "//" {
+		m.prefix = "/"
+	}
+	if opts.Dev {
+		return m, nil
+	}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".br") {
+			return nil
+		}
+		return m.add(p, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// add hashes the file named name, and prepares its compressed variants.
+func (m *Manager) add(name string, d fs.DirEntry) error {
+	data, err := fs.ReadFile(m.fsys, name)
+	if err != nil {
+		return err
+	}
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:hashLen]
+	ext := path.Ext(name)
+	f := &file{
+		name:    name,
+		hashed:  strings.TrimSuffix(name, ext) + "." + hash + ext,
+		hash:    hash,
+		modTime: info.ModTime(),
+	}
+	if _, err := fs.Stat(m.fsys, name+".br"); err == nil {
+		f.brFile = name + ".br"
+	}
+	if _, err := fs.Stat(m.fsys, name+".gz"); err == nil {
+		f.gzFile = name + ".gz"
+	} else if isCompressible(contentType(name)) {
+		buf := &bytes.Buffer{}
+		gz, _ := gzip.NewWriterLevel(buf, gzip.BestCompression)
+		_, _ = gz.Write(data)
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		if buf.Len() < len(data) {
+			f.gzip = buf.Bytes()
+		}
+	}
+	m.files[f.name] = f
+	m.hashed[f.hashed] = f
+	return nil
+}
+
+// Path returns the URL path of the file named name, relative to the file
+// system. The fingerprinted name is used when the file is known, e.g.
+//
+//	m.Path("css/app.css") == "/static/css/app.3f9a2c1d.css"
+func (m *Manager) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if f, ok := m.files[name]; ok {
+		return m.prefix + f.hashed
+	}
+	return m.prefix + name
+}
+
+// ServeHTTP serves the file named by the request path, which must have the
+// prefix stripped. Directories are served by their index.html, the root
+// included.
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if m.opts.Dev {
+		w.Header().Set("Cache-Control", "no-cache")
+		http.FileServer(http.FS(m.fsys)).ServeHTTP(w, r)
+		return
+	}
+	f, ok := m.hashed[name]
+	cache := immutable
+	if !ok {
+		f, ok = m.files[name]
+		cache = revalidate
+	}
+	if !ok {
+		// directories are served by their index.html, like http.FileServer
+		// does. The empty name is the root the Manager is mounted on.
+		if index, isDir := m.files[path.Join(name, "index.html")]; isDir {
+			if name != "" && !strings.HasSuffix(r.URL.Path, "/") {
+				localRedirect(w, r, path.Base(r.URL.Path)+"/")
+				return
+			}
+			f, ok = index, true
+		}
+	}
+	if !ok {
+		m.serveUnindexed(w, r, name)
+		return
+	}
+	h := w.Header()
+	h.Set("Cache-Control", cache)
+	h.Set("Vary", "Accept-Encoding")
+	h.Set("Content-Type", contentType(f.name))
+
+	// each encoding has its own ETag, a cached body is revalidated only for
+	// the same encoding.
+	accept := r.Header.Get("Accept-Encoding")
+	switch {
+	case f.brFile != "" && accepts(accept, "br"):
+		h.Set("Content-Encoding", "br")
+		h.Set("ETag", f.etag("-br"))
+		m.serveFile(w, r, f, f.brFile)
+	case f.gzFile != "" && accepts(accept, "gzip"):
+		h.Set("Content-Encoding", "gzip")
+		h.Set("ETag", f.etag("-gz"))
+		m.serveFile(w, r, f, f.gzFile)
+	case f.gzip != nil && accepts(accept, "gzip"):
+		h.Set("Content-Encoding", "gzip")
+		h.Set("ETag", f.etag("-gz"))
+		http.ServeContent(w, r, f.name, f.modTime, bytes.NewReader(f.gzip))
+	default:
+		h.Set("ETag", f.etag(""))
+		m.serveFile(w, r, f, f.name)
+	}
+}
+
+// serveUnindexed serves the file named name when it was added to the file
+// system after New. It has no fingerprinted name nor compressed variant, and
+// like in New the precompressed variants are not served by their own name.
+func (m *Manager) serveUnindexed(w http.ResponseWriter, r *http.Request, name string) {
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".br") {
+		http.NotFound(w, r)
+		return
+	}
+	info, err := fs.Stat(m.fsys, name)
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	h := w.Header()
+	h.Set("Cache-Control", revalidate)
+	h.Set("Content-Type", contentType(name))
+	m.serveFile(w, r, &file{name: name, modTime: info.ModTime()}, name)
+}
+
+// etag returns the ETag of the body of f in the encoding named by suffix.
+func (f *file) etag(suffix string) string {
+	return `"` + f.hash + suffix + `"`
+}
+
+// localRedirect redirects to the path relative to the request, the Manager
+// does not know the prefix it is mounted on when it is stripped.
+func localRedirect(w http.ResponseWriter, r *http.Request, target string) {
+	if q := r.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// serveFile writes the content of the file named name in the file system.
+func (m *Manager) serveFile(w http.ResponseWriter, r *http.Request, f *file, name string) {
+	src, err := m.fsys.Open(name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer src.Close()
+	rs, ok := src.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		rs = bytes.NewReader(data)
+	}
+	http.ServeContent(w, r, f.name, f.modTime, rs)
+}
+
+// contentType returns the content type of name deduced from its extension.
+func contentType(name string) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func isCompressible(ctype string) bool {
+	for _, v := range compressible {
+		if strings.HasPrefix(ctype, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// accepts returns true if the Accept-Encoding header value accept lists
+// encoding.
+func accepts(accept, encoding string) bool {
+	for _, v := range strings.Split(accept, ",") {
+		parts := strings.Split(strings.TrimSpace(v), ";")
+		if strings.TrimSpace(parts[0]) != encoding {
+			continue
+		}
+		if len(parts) > 1 && strings.Replace(strings.TrimSpace(parts[1]), " ", "", -1) == "q=0" {
+			return false
+		}
+		return true
+	}
+	return false
+}
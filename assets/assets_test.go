@@ -0,0 +1,183 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestManager(t *testing.T) {
+	css := strings.Repeat("body { color: red; }\n", 20)
+	fsys := fstest.MapFS{
+		"css/app.css":     &fstest.MapFile{Data: []byte(css)},
+		"js/app.js":       &fstest.MapFile{Data: []byte("var a = 1;")},
+		"js/app.js.br":    &fstest.MapFile{Data: []byte("brotli")},
+		"img/logo.png":    &fstest.MapFile{Data: []byte("png")},
+		"robots.txt.gz":   &fstest.MapFile{Data: []byte("orphan")},
+		"fonts/font.bin":  &fstest.MapFile{Data: []byte("font")},
+		"docs/index.html": &fstest.MapFile{Data: []byte("docs")},
+		"index.html":      &fstest.MapFile{Data: []byte("home")},
+	}
+	m, err := New(fsys, "static", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := m.Path("css/app.css")
+	if !strings.HasPrefix(p, "/static/css/app.") || !strings.HasSuffix(p, ".css") || p == "/static/css/app.css" {
+		t.Fatalf("expected fingerprinted path got %s", p)
+	}
+	if p != m.Path("/css/app.css") {
+		t.Errorf("expected leading slash to be ignored")
+	}
+	if p := m.Path("missing.css"); p != "/static/missing.css" {
+		t.Errorf("expected /static/missing.css got %s", p)
+	}
+
+	get := func(path, accept string, h http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		for k, v := range h {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		http.StripPrefix("/static/", m).ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(p, "", nil)
+	if w.Code != http.StatusOK || w.Body.String() != css {
+		t.Fatalf("expected the file got %d %q", w.Code, w.Body)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != immutable {
+		t.Errorf("expected %s got %s", immutable, cc)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("expected text/css got %s", ct)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an etag")
+	}
+
+	w = get("/static/css/app.css", "", nil)
+	if cc := w.Header().Get("Cache-Control"); cc != revalidate {
+		t.Errorf("expected %s got %s", revalidate, cc)
+	}
+	if w.Header().Get("ETag") != etag {
+		t.Errorf("expected the same etag for the plain name")
+	}
+
+	w = get(p, "", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected %d got %d", http.StatusNotModified, w.Code)
+	}
+
+	w = get(p, "gzip, deflate", nil)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding got %q", w.Header().Get("Content-Encoding"))
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != css {
+		t.Errorf("expected %q got %q", css, b)
+	}
+	// the compressed body has its own etag, the identity body is not reused.
+	gzEtag := w.Header().Get("ETag")
+	if gzEtag == "" || gzEtag == etag {
+		t.Errorf("expected a gzip etag different from %s got %s", etag, gzEtag)
+	}
+	if w := get(p, "gzip", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusOK {
+		t.Errorf("expected the identity etag not to match the gzip body got %d", w.Code)
+	}
+	if w := get(p, "gzip", http.Header{"If-None-Match": {gzEtag}}); w.Code != http.StatusNotModified {
+		t.Errorf("expected %d got %d", http.StatusNotModified, w.Code)
+	}
+	if w := get(p, "gzip;q=0", nil); w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected no encoding when gzip is refused")
+	}
+
+	w = get(m.Path("js/app.js"), "gzip, br", nil)
+	if w.Header().Get("Content-Encoding") != "br" || w.Body.String() != "brotli" {
+		t.Errorf("expected the precompressed brotli file got %q %q", w.Header().Get("Content-Encoding"), w.Body)
+	}
+	if e := w.Header().Get("ETag"); !strings.HasSuffix(e, `-br"`) {
+		t.Errorf("expected a brotli etag got %s", e)
+	}
+	if w := get("/static/js/app.js.br", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected compressed files not to be served directly got %d", w.Code)
+	}
+
+	w = get(m.Path("img/logo.png"), "gzip", nil)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "png" {
+		t.Errorf("expected images not to be compressed")
+	}
+
+	if w := get("/static/missing.css", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+
+	// directories are served by their index.html.
+	if w := get("/static/docs/", "", nil); w.Code != http.StatusOK || w.Body.String() != "docs" {
+		t.Errorf("expected the index got %d %q", w.Code, w.Body)
+	}
+	w = get("/static/docs", "", nil)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "docs/" {
+		t.Errorf("expected a redirect to docs/ got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := get("/static/fonts/", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+	// the root the Manager is mounted on is not redirected.
+	if w := get("/static/", "", nil); w.Code != http.StatusOK || w.Body.String() != "home" {
+		t.Errorf("expected the root index got %d %q %s", w.Code, w.Body, w.Header().Get("Location"))
+	}
+
+	// files added after New are served by their plain name.
+	fsys["late.txt"] = &fstest.MapFile{Data: []byte("late")}
+	w = get("/static/late.txt", "gzip", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "late" {
+		t.Errorf("expected the new file got %d %q", w.Code, w.Body)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != revalidate {
+		t.Errorf("expected %s got %s", revalidate, cc)
+	}
+	if m.Path("late.txt") != "/static/late.txt" {
+		t.Errorf("expected the plain path got %s", m.Path("late.txt"))
+	}
+}
+
+func TestManagerDev(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.css": &fstest.MapFile{Data: []byte("body{}")},
+	}
+	m, err := New(fsys, "/static/", Options{Dev: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := m.Path("app.css"); p != "/static/app.css" {
+		t.Errorf("expected /static/app.css got %s", p)
+	}
+	req := httptest.NewRequest("GET", "/app.css", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Body.String() != "body{}" {
+		t.Errorf("expected body{} got %s", w.Body)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected no-cache got %s", cc)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 
 	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/i18n"
 	"github.com/gernest/utron/logger"
 	"github.com/gernest/utron/models"
 	"github.com/gernest/utron/view"
@@ -55,21 +56,42 @@ type Context struct {
 	// Template is the name of the template to be rendered by the view
 	Template string
 
+	// Layout is the name of the layout the Template is rendered in. When empty
+	// the layout declared by the template, if any, is used. This only works
+	// when the view supports layouts.
+	Layout string
+
 	// Cfg is the application configuration
 	Cfg *config.Config
 
 	//DB is the database stuff, with all models registered
 	DB *models.Model
 
+	// DBs are the named databases, e.g. DBs["analytics"].
+	DBs map[string]*models.Model
+
 	Log logger.Logger
 
 	SessionStore sessions.Store
 
-	request    *http.Request
-	response   http.ResponseWriter
-	out        io.ReadWriter
-	isCommited bool
-	view       view.View
+	// I18n holds the message catalogs used to translate messages, it is nil
+	// when translations are disabled.
+	I18n *i18n.Bundle
+
+	request     *http.Request
+	response    http.ResponseWriter
+	out         io.ReadWriter
+	isCommited  bool
+	view        view.View
+	status      int
+	wroteHeader bool
+	session     *Session
+	flasher     *Flasher
+	flashSess   *Session
+	flashes     Flashes
+	locale      string
+	commitHooks []func(int) error
+	doneHooks   []func(error)
 }
 
 // NewContext creates new context for the given w and r
@@ -142,6 +164,8 @@ func (c *Context) SetData(key, value interface{}) {
 //	 * ResponseWriter by passing http.ResponseVritter
 //	 * view by passing View
 //	 * response status code by passing an int
+//
+// The status code is written to the response when Commit is called.
 func (c *Context) Set(value interface{}) {
 	switch value := value.(type) {
 	case view.View:
@@ -151,10 +175,15 @@ func (c *Context) Set(value interface{}) {
 	case http.ResponseWriter:
 		c.response = value
 	case int:
-		c.response.WriteHeader(value)
+		c.status = value
 	}
 }
 
+// View returns the view set on the context, if any.
+func (c *Context) View() view.View {
+	return c.view
+}
+
 // SetHeader sets response header
 func (c *Context) SetHeader(key, value string) {
 	c.response.Header().Set(key, value)
@@ -166,25 +195,76 @@ func (c *Context) SetHeader(key, value string) {
 // If there is a view, and the template is specified the the view is rendered and its
 // output is written to the response, otherwise any data written to the context is written to the
 // ResponseWriter.
+//
+// Flash messages from previous requests are passed to the template under the
+// FlashContextKey, and the locale of the request under Locale. Pending flash
+// messages and the default session are saved before anything is written, if
+// they were modified. The OnCommit functions are called after rendering,
+// before the sessions are saved, so nothing is saved when they fail, e.g. when
+// the transaction of the request can't be committed.
 func (c *Context) Commit() error {
 	if c.isCommited {
 		return errors.New("already committed")
 	}
+	out := c.out
 	if c.Template != "" && c.view != nil {
-		out := &bytes.Buffer{}
-		err := c.view.Render(out, c.Template, c.Data)
+		if err := c.loadFlashes(); err != nil {
+			return err
+		}
+		c.loadLocale()
+		out = &bytes.Buffer{}
+		var err error
+		if lv, ok := c.view.(view.LayoutView); ok {
+			err = lv.RenderLayout(out, c.Layout, c.Template, c.Data)
+		} else {
+			err = c.view.Render(out, c.Template, c.Data)
+		}
 		if err != nil {
 			return err
 		}
-		_, _ = io.Copy(c.response, out)
-	} else {
-		_, _ = io.Copy(c.response, c.out)
 	}
+	if err := c.runCommitHooks(c.status); err != nil {
+		return err
+	}
+	if err := c.saveSessions(); err != nil {
+		return err
+	}
+	c.writeHeader()
+	_, _ = io.Copy(c.response, out)
 	c.isCommited = true
 	return nil
 }
 
-// Redirect redirects request to url using code as status code.
+// IsCommitted returns true if Commit was called successfully.
+func (c *Context) IsCommitted() bool {
+	return c.isCommited
+}
+
+// writeHeader writes the status code set on the context, if any.
+func (c *Context) writeHeader() {
+	if c.status != 0 && !c.wroteHeader {
+		c.response.WriteHeader(c.status)
+	}
+	c.wroteHeader = true
+}
+
+// Redirect redirects request to url using code as status code. The OnCommit
+// functions are called, and pending flash messages and the default session are
+// then saved before redirecting.
 func (c *Context) Redirect(url string, code int) {
+	if err := c.runCommitHooks(code); err != nil {
+		if c.Log != nil {
+			c.Log.Errors(err)
+		}
+		http.Error(c.Response(), http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		c.status = http.StatusInternalServerError
+		c.wroteHeader = true
+		return
+	}
+	if err := c.saveSessions(); err != nil && c.Log != nil {
+		c.Log.Errors(err)
+	}
 	http.Redirect(c.Response(), c.Request(), url, code)
+	c.status = code
+	c.wroteHeader = true
 }
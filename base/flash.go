@@ -0,0 +1,266 @@
+package base
+
+import "encoding/gob"
+
+const (
+	// FlashSuccess is the kind of success flash messages
+	FlashSuccess = "FlashSuccess"
+
+	// FlashWarn is the kind of warning flash messages
+	FlashWarn = "FlashWarn"
+
+	// FlashErr is the kind of error flash messages
+	FlashErr = "FlashError"
+
+	// defaultFlashSession is the session name used for flash messages when
+	// Config.Flash is not set.
+	defaultFlashSession = "_flash"
+
+	// defaultFlashContextKey is the key in Context.Data holding flash messages
+	// when Config.FlashContextKey is not set.
+	defaultFlashContextKey = "Flash"
+
+	// flashValuesKey is the session value key under which flash messages are
+	// stored.
+	flashValuesKey = "flashes"
+)
+
+func init() {
+	gob.Register(&Flash{})
+	gob.Register(Flashes{})
+
+	// the names the flash package registered its types with, before they
+	// moved here.
+	gob.RegisterName("*github.com/gernest/utron/flash.Flash", &legacyFlash{})
+	gob.RegisterName("github.com/gernest/utron/flash.Flashes", legacyFlashes{})
+}
+
+// legacyFlash and legacyFlashes decode the flash messages saved in sessions by
+// older releases, when the types were defined in the flash package.
+type legacyFlash struct {
+	Kind    string
+	Message string
+}
+
+type legacyFlashes []*legacyFlash
+
+// Flash is a message which is kept in the session until it is displayed on
+// a following request.
+//
+// Kind can be any string, the FlashSuccess, FlashWarn and FlashErr constants
+// are provided for the common cases. Data holds an optional structured payload,
+// its type must be registered with encoding/gob.
+type Flash struct {
+	Kind    string
+	Message string
+	Data    interface{}
+}
+
+// Flashes is a collection of flash messages. The methods are meant to be used
+// from templates, for instance
+//	{{range .Flash.Kind "notice"}}{{.Message}}{{end}}
+type Flashes []*Flash
+
+// Kind returns flash messages of the given kind.
+func (f Flashes) Kind(kind string) Flashes {
+	var rst Flashes
+	for _, v := range f {
+		if v.Kind == kind {
+			rst = append(rst, v)
+		}
+	}
+	return rst
+}
+
+// ToFlashes returns the flash messages of the session value v. It accepts the
+// flash messages saved by older releases, returns false when v holds none.
+func ToFlashes(v interface{}) (Flashes, bool) {
+	switch f := v.(type) {
+	case Flashes:
+		return f, true
+	case legacyFlashes:
+		rst := make(Flashes, 0, len(f))
+		for _, v := range f {
+			if v != nil {
+				rst = append(rst, &Flash{Kind: v.Kind, Message: v.Message})
+			}
+		}
+		return rst, true
+	case *legacyFlash:
+		if f == nil {
+			return nil, false
+		}
+		return Flashes{{Kind: f.Kind, Message: f.Message}}, true
+	case *Flash:
+		if f == nil {
+			return nil, false
+		}
+		return Flashes{f}, true
+	}
+	return nil, false
+}
+
+// Kinds returns the distinct kinds of flash messages, in the order they were
+// added.
+func (f Flashes) Kinds() []string {
+	var kinds []string
+	seen := make(map[string]bool)
+	for _, v := range f {
+		if !seen[v.Kind] {
+			seen[v.Kind] = true
+			kinds = append(kinds, v.Kind)
+		}
+	}
+	return kinds
+}
+
+// ByKind groups flash messages by kind.
+func (f Flashes) ByKind() map[string]Flashes {
+	rst := make(map[string]Flashes)
+	for _, v := range f {
+		rst[v.Kind] = append(rst[v.Kind], v)
+	}
+	return rst
+}
+
+// Success returns success flash messages.
+func (f Flashes) Success() Flashes {
+	return f.Kind(FlashSuccess)
+}
+
+// Warnings returns warning flash messages.
+func (f Flashes) Warnings() Flashes {
+	return f.Kind(FlashWarn)
+}
+
+// Errors returns error flash messages.
+func (f Flashes) Errors() Flashes {
+	return f.Kind(FlashErr)
+}
+
+// Flasher accumulates flash messages for the next request. The messages are
+// saved when the context is committed or redirected.
+type Flasher struct {
+	pending Flashes
+}
+
+// Flash returns the Flasher of the request.
+func (ctx *Context) Flash() *Flasher {
+	if ctx.flasher == nil {
+		ctx.flasher = &Flasher{}
+	}
+	return ctx.flasher
+}
+
+// Add adds a flash message of the given kind.
+func (f *Flasher) Add(kind, message string) {
+	f.AddData(kind, message, nil)
+}
+
+// AddData adds a flash message of the given kind with a structured payload.
+func (f *Flasher) AddData(kind, message string, data interface{}) {
+	f.pending = append(f.pending, &Flash{Kind: kind, Message: message, Data: data})
+}
+
+// Success adds success flash message
+func (f *Flasher) Success(msg string) {
+	f.Add(FlashSuccess, msg)
+}
+
+// Err adds error flash message
+func (f *Flasher) Err(msg string) {
+	f.Add(FlashErr, msg)
+}
+
+// Warn adds warning flash message
+func (f *Flasher) Warn(msg string) {
+	f.Add(FlashWarn, msg)
+}
+
+// Pending returns flash messages added during this request.
+func (f *Flasher) Pending() Flashes {
+	return f.pending
+}
+
+// Flashes returns flash messages saved by previous requests. The messages are
+// removed from the session, so they are only returned for one request. It is
+// not an error when there are no flash messages.
+func (ctx *Context) Flashes() (Flashes, error) {
+	if ctx.flashes != nil {
+		return ctx.flashes, nil
+	}
+	ss, err := ctx.flashSession()
+	if err != nil {
+		return nil, err
+	}
+	ctx.flashes = Flashes{}
+	if v, ok := ToFlashes(ss.Values[flashValuesKey]); ok {
+		ctx.flashes = v
+		ss.Delete(flashValuesKey)
+	}
+	return ctx.flashes, nil
+}
+
+// flashSession returns the session storing flash messages, which is named
+// after Config.Flash.
+func (ctx *Context) flashSession() (*Session, error) {
+	if ctx.flashSess != nil {
+		return ctx.flashSess, nil
+	}
+	if ctx.SessionStore == nil {
+		return nil, errNoStore
+	}
+	name := defaultFlashSession
+	if ctx.Cfg != nil && ctx.Cfg.Flash != "" {
+		name = ctx.Cfg.Flash
+	}
+	s, err := ctx.SessionStore.New(ctx.Request(), name)
+	if s == nil {
+		return nil, err
+	}
+	ctx.flashSess = &Session{Session: s}
+	return ctx.flashSess, nil
+}
+
+// flashContextKey returns the key in Data under which flash messages are
+// passed to templates.
+func (ctx *Context) flashContextKey() string {
+	if ctx.Cfg != nil && ctx.Cfg.FlashContextKey != "" {
+		return ctx.Cfg.FlashContextKey
+	}
+	return defaultFlashContextKey
+}
+
+// loadFlashes sets flash messages from previous requests in Data, so they are
+// available to templates.
+func (ctx *Context) loadFlashes() error {
+	if ctx.SessionStore == nil {
+		return nil
+	}
+	f, err := ctx.Flashes()
+	if err != nil {
+		return err
+	}
+	if ctx.Data == nil {
+		ctx.Data = make(map[string]interface{})
+	}
+	ctx.Data[ctx.flashContextKey()] = f
+	return nil
+}
+
+// saveFlashes stores pending flash messages in the flash session.
+func (ctx *Context) saveFlashes() error {
+	if ctx.flasher != nil && len(ctx.flasher.pending) > 0 {
+		ss, err := ctx.flashSession()
+		if err != nil {
+			return err
+		}
+		var flashes Flashes
+		if v, ok := ToFlashes(ss.Values[flashValuesKey]); ok {
+			flashes = v
+		}
+		ss.Set(flashValuesKey, append(flashes, ctx.flasher.pending...))
+		ctx.flasher.pending = nil
+	}
+	return ctx.saveIfModified(ctx.flashSess)
+}
@@ -0,0 +1,115 @@
+package base
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gernest/utron/config"
+	"github.com/gorilla/sessions"
+)
+
+type flashPayload struct {
+	ID int
+}
+
+func init() {
+	gob.Register(&flashPayload{})
+}
+
+// flashView renders the flash messages found in data.
+type flashView struct {
+	key string
+}
+
+func (f *flashView) Render(out io.Writer, name string, data interface{}) error {
+	flashes := data.(map[string]interface{})[f.key].(Flashes)
+	for _, kind := range flashes.Kinds() {
+		for _, v := range flashes.Kind(kind) {
+			fmt.Fprintf(out, "%s:%s;", kind, v.Message)
+		}
+	}
+	return nil
+}
+
+func TestContextFlash(t *testing.T) {
+	store := sessions.NewCookieStore([]byte("ePAPW9vJv7gHoftvQTyNj5VkWB52mlza"))
+	cfg := config.DefaultConfig()
+	newCtx := func(r *http.Request) (*Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c := NewContext(w, r)
+		c.Cfg = cfg
+		c.SessionStore = store
+		c.Set(&flashView{key: cfg.FlashContextKey})
+		return c, w
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	ctx, w := newCtx(req)
+	ctx.Flash().Success("saved")
+	ctx.Flash().Add("notice", "hello")
+	ctx.Flash().AddData("created", "item", &flashPayload{ID: 1})
+	ctx.Redirect("/next", http.StatusFound)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != cfg.Flash {
+		t.Fatalf("expected flash cookie got %v", cookies)
+	}
+
+	req, _ = http.NewRequest("GET", "/next", nil)
+	req.AddCookie(cookies[0])
+	ctx, w = newCtx(req)
+	ctx.Template = "index"
+	if err := ctx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	expect := "FlashSuccess:saved;notice:hello;created:item;"
+	if w.Body.String() != expect {
+		t.Errorf("expected %s got %s", expect, w.Body.String())
+	}
+	flashes := ctx.Data[cfg.FlashContextKey].(Flashes)
+	if p := flashes.Kind("created")[0].Data.(*flashPayload); p.ID != 1 {
+		t.Errorf("expected 1 got %d", p.ID)
+	}
+	if len(flashes.Success()) != 1 {
+		t.Errorf("expected 1 got %d", len(flashes.Success()))
+	}
+
+	// flash messages are shown only once
+	req, _ = http.NewRequest("GET", "/next", nil)
+	req.AddCookie(w.Result().Cookies()[0])
+	ctx, _ = newCtx(req)
+	f, err := ctx.Flashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f) != 0 {
+		t.Errorf("expected no flashes got %d", len(f))
+	}
+}
+
+// legacySession is the gob encoding of session values holding the flash
+// messages saved by the flash package of older releases.
+const legacySession = "DX8EAQL/gAABEAEQAABT/4AAAQZzdHJpbmcMCQAHZmxhc2hlcyZnaXRodWIuY29tL2dlcm5lc3QvdXRyb24vZmxhc2guRmxhc2hlc/+DAgEBB0ZsYXNoZXMB/4QAAf+CAAAh/4EDAQL/ggABAgEES2luZAEMAAEHTWVzc2FnZQEMAAAAG/+EGAABAQxGbGFzaFN1Y2Nlc3MBBXNhdmVkAA=="
+
+func TestLegacyFlashes(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(legacySession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[interface{}]interface{})
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		t.Fatal(err)
+	}
+	flashes, ok := ToFlashes(values["flashes"])
+	if !ok || len(flashes) != 1 || flashes[0].Kind != FlashSuccess || flashes[0].Message != "saved" {
+		t.Errorf("expected the old flash message got %v", flashes)
+	}
+	if _, ok = ToFlashes("flashes"); ok {
+		t.Error("expected no flash messages")
+	}
+}
@@ -0,0 +1,44 @@
+package base
+
+import "net/http"
+
+// OnCommit registers fn to be called with the response status just before the
+// response is written by Commit or Redirect. When fn returns an error nothing
+// is written, Commit returns the error and Redirect responds with an internal
+// server error instead.
+//
+// The status is http.StatusOK when none was set.
+func (c *Context) OnCommit(fn func(status int) error) {
+	c.commitHooks = append(c.commitHooks, fn)
+}
+
+// OnDone registers fn to be called once the request has been handled, whether
+// a response was committed or not. err is not nil when the handler panicked.
+func (c *Context) OnDone(fn func(err error)) {
+	c.doneHooks = append(c.doneHooks, fn)
+}
+
+// Done calls the functions registered with OnDone, in reverse order. It is
+// called by the router after the middlewares and the controller have run.
+func (c *Context) Done(err error) {
+	hooks := c.doneHooks
+	c.doneHooks = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i](err)
+	}
+}
+
+// runCommitHooks calls the functions registered with OnCommit with status.
+func (c *Context) runCommitHooks(status int) error {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	hooks := c.commitHooks
+	c.commitHooks = nil
+	for _, fn := range hooks {
+		if err := fn(status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
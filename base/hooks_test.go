@@ -0,0 +1,85 @@
+package base
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gernest/utron/config"
+	"github.com/gorilla/sessions"
+)
+
+func TestHooks(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	ctx := NewContext(w, req)
+	var calls []string
+	ctx.OnCommit(func(status int) error {
+		if status != http.StatusOK {
+			t.Errorf("expected %d got %d", http.StatusOK, status)
+		}
+		calls = append(calls, "commit")
+		return nil
+	})
+	ctx.OnDone(func(err error) { calls = append(calls, "done 1") })
+	ctx.OnDone(func(err error) { calls = append(calls, "done 2") })
+	if err := ctx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Done(nil)
+	ctx.Done(nil)
+	expect := []string{"commit", "done 2", "done 1"}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Errorf("expected %v got %v", expect, calls)
+	}
+
+	// a failing hook stops the response.
+	w = httptest.NewRecorder()
+	ctx = NewContext(w, req)
+	_, _ = ctx.Write([]byte("hello"))
+	ctx.OnCommit(func(int) error { return errors.New("failed") })
+	if err := ctx.Commit(); err == nil {
+		t.Error("expected an error")
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected nothing written got %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	ctx = NewContext(w, req)
+	var status int
+	ctx.OnCommit(func(s int) error {
+		status = s
+		return errors.New("failed")
+	})
+	ctx.Redirect("/home", http.StatusFound)
+	if status != http.StatusFound {
+		t.Errorf("expected %d got %d", http.StatusFound, status)
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestFailedCommitSavesNoSession(t *testing.T) {
+	store := sessions.NewCookieStore([]byte("ePAPW9vJv7gHoftvQTyNj5VkWB52mlza"))
+	req, _ := http.NewRequest("POST", "/items", nil)
+	for _, redirect := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		ctx := NewContext(w, req)
+		ctx.Cfg = config.DefaultConfig()
+		ctx.SessionStore = store
+		ctx.Flash().Success("saved!")
+		ctx.OnCommit(func(int) error { return errors.New("commit failed") })
+		if redirect {
+			ctx.Redirect("/items", http.StatusFound)
+		} else if err := ctx.Commit(); err == nil {
+			t.Error("expected an error")
+		}
+		if cookies := w.Result().Cookies(); len(cookies) != 0 {
+			t.Errorf("redirect %v: expected no session saved got %v", redirect, cookies)
+		}
+	}
+}
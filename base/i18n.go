@@ -0,0 +1,75 @@
+package base
+
+import "net/http"
+
+const (
+	// defaultLocaleCookie is the cookie and session value name holding the
+	// locale when Config.LocaleCookie is not set.
+	defaultLocaleCookie = "locale"
+
+	// localeContextKey is the key in Context.Data holding the locale when a
+	// template is rendered.
+	localeContextKey = "Locale"
+)
+
+// Locale returns the locale of the request. It is detected once from the URL
+// prefix, the locale cookie, the session and the Accept-Language header, in
+// that order. Locale returns an empty string when translations are disabled.
+func (c *Context) Locale() string {
+	if c.locale != "" || c.I18n == nil {
+		return c.locale
+	}
+	var sess string
+	if s, err := c.Session(); err == nil {
+		sess, _ = s.GetString(c.localeCookie())
+	}
+	c.locale = c.I18n.Detect(c.request, c.localeCookie(), sess)
+	return c.locale
+}
+
+// SetLocale changes the locale of the request, and remembers it for following
+// requests in the session and the locale cookie.
+func (c *Context) SetLocale(locale string) {
+	if c.I18n != nil {
+		locale = c.I18n.Match(locale)
+	}
+	c.locale = locale
+	if s, err := c.Session(); err == nil {
+		s.Set(c.localeCookie(), locale)
+	}
+	cookie := &http.Cookie{
+		Name:     c.localeCookie(),
+		Value:    locale,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+	}
+	http.SetCookie(c.response, cookie)
+}
+
+// T returns the message key translated to the locale of the request, see
+// i18n.Bundle.T. The key is returned when translations are disabled.
+func (c *Context) T(key string, args ...interface{}) string {
+	if c.I18n == nil {
+		return key
+	}
+	return c.I18n.T(c.Locale(), key, args...)
+}
+
+func (c *Context) localeCookie() string {
+	if c.Cfg != nil && c.Cfg.LocaleCookie != "" {
+		return c.Cfg.LocaleCookie
+	}
+	return defaultLocaleCookie
+}
+
+// loadLocale passes the locale to the template, so it can be used with the t
+// template function.
+func (c *Context) loadLocale() {
+	if c.I18n == nil {
+		return
+	}
+	if _, ok := c.Data[localeContextKey]; !ok {
+		c.Data[localeContextKey] = c.Locale()
+	}
+}
@@ -0,0 +1,77 @@
+package base
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/i18n"
+	"github.com/gorilla/sessions"
+)
+
+func TestContextLocale(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	ctx := NewContext(httptest.NewRecorder(), req)
+	if l := ctx.Locale(); l != "" {
+		t.Errorf("expected no locale got %s", l)
+	}
+	if v := ctx.T("home.title"); v != "home.title" {
+		t.Errorf("expected the key got %s", v)
+	}
+
+	bundle := i18n.NewBundle("en")
+	_ = bundle.Add("en", map[string]interface{}{"items": map[string]interface{}{"one": "%d item", "other": "%d items"}})
+	_ = bundle.Add("fr", map[string]interface{}{"items": map[string]interface{}{"one": "%d article", "other": "%d articles"}})
+	store := sessions.NewCookieStore([]byte("ePAPW9vJv7gHoftvQTyNj5VkWB52mlza"))
+	cfg := &config.Config{SessionName: "_utron"}
+	newCtx := func(r *http.Request) (*Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c := NewContext(w, r)
+		c.Cfg = cfg
+		c.SessionStore = store
+		c.I18n = bundle
+		return c, w
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "fr-FR, en;q=0.5")
+	ctx, _ = newCtx(req)
+	if v := ctx.T("items", 2); v != "2 articles" {
+		t.Errorf("expected 2 articles got %s", v)
+	}
+
+	// the chosen locale is remembered by the session and the cookie.
+	req, _ = http.NewRequest("GET", "/", nil)
+	ctx, w := newCtx(req)
+	ctx.SetLocale("fr-BE")
+	if l := ctx.Locale(); l != "fr" {
+		t.Errorf("expected fr got %s", l)
+	}
+	if err := ctx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var sessionCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == cfg.SessionName {
+			sessionCookie = c
+		}
+	}
+	if sessionCookie == nil {
+		t.Fatal("expected the session to be saved")
+	}
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.AddCookie(sessionCookie)
+	ctx, _ = newCtx(req)
+	if l := ctx.Locale(); l != "fr" {
+		t.Errorf("expected fr from the session got %s", l)
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: defaultLocaleCookie, Value: "fr"})
+	ctx, _ = newCtx(req)
+	ctx.loadLocale()
+	if ctx.Data[localeContextKey] != "fr" {
+		t.Errorf("expected the locale in the data got %v", ctx.Data[localeContextKey])
+	}
+}
@@ -2,6 +2,7 @@ package base
 
 import (
 	"errors"
+	"time"
 
 	"github.com/gorilla/sessions"
 )
@@ -9,24 +10,34 @@ import (
 var errNoStore = errors.New("no session store was found")
 
 //NewSession returns a new browser session whose key is set to name. This only
-//works when the *Context.SessionStore is not nil.
+//works when the *Context.SessionStore is not nil. When name is empty the
+//SessionName from the configuration is used.
 //
 // The session returned is from grorilla/sessions package.
 func (ctx *Context) NewSession(name string) (*sessions.Session, error) {
 	if ctx.SessionStore != nil {
-		return ctx.SessionStore.New(ctx.Request(), name)
+		return ctx.SessionStore.New(ctx.Request(), ctx.sessionName(name))
 	}
 	return nil, errNoStore
 }
 
-//GetSession retrieves session with a given name.
+//GetSession retrieves session with a given name. When name is empty the
+//SessionName from the configuration is used.
 func (ctx *Context) GetSession(name string) (*sessions.Session, error) {
 	if ctx.SessionStore != nil {
-		return ctx.SessionStore.New(ctx.Request(), name)
+		return ctx.SessionStore.New(ctx.Request(), ctx.sessionName(name))
 	}
 	return nil, errNoStore
 }
 
+// sessionName returns name, or the configured SessionName when name is empty.
+func (ctx *Context) sessionName(name string) string {
+	if name == "" && ctx.Cfg != nil {
+		return ctx.Cfg.SessionName
+	}
+	return name
+}
+
 //SaveSession saves the given session.
 func (ctx *Context) SaveSession(s *sessions.Session) error {
 	if ctx.SessionStore != nil {
@@ -34,3 +45,141 @@ func (ctx *Context) SaveSession(s *sessions.Session) error {
 	}
 	return errNoStore
 }
+
+// Session is the default browser session of a request. It wraps the
+// gorilla/sessions session, and keeps track of changes so the session is only
+// saved when it was modified.
+//
+// Changes made directly on Values are not tracked, use Set and Delete or call
+// MarkModified afterwards.
+type Session struct {
+	*sessions.Session
+	modified bool
+}
+
+// Session returns the default session of the request. The session is named
+// after SessionName in the configuration, and is loaded only once per request.
+//
+// The session is saved automatically when the context is committed or
+// redirected, if it was modified. When the session cookie can not be decoded,
+// for instance after the keys were changed, a fresh session is returned.
+func (ctx *Context) Session() (*Session, error) {
+	if ctx.session != nil {
+		return ctx.session, nil
+	}
+	if ctx.SessionStore == nil {
+		return nil, errNoStore
+	}
+	s, err := ctx.SessionStore.New(ctx.Request(), ctx.sessionName(""))
+	if s == nil {
+		return nil, err
+	}
+	ctx.session = &Session{Session: s}
+	return ctx.session, nil
+}
+
+// saveSessions saves pending flash messages and the default session, if they
+// were modified.
+func (ctx *Context) saveSessions() error {
+	if err := ctx.saveFlashes(); err != nil {
+		return err
+	}
+	return ctx.saveIfModified(ctx.session)
+}
+
+// saveIfModified saves s if it is not nil and was modified.
+func (ctx *Context) saveIfModified(s *Session) error {
+	if s == nil || !s.modified {
+		return nil
+	}
+	if err := ctx.SaveSession(s.Session); err != nil {
+		return err
+	}
+	s.modified = false
+	return nil
+}
+
+// IsModified returns true if the session has changes which are not saved yet.
+func (s *Session) IsModified() bool {
+	return s.modified
+}
+
+// MarkModified flags the session to be saved.
+func (s *Session) MarkModified() {
+	s.modified = true
+}
+
+// Set stores value under key.
+func (s *Session) Set(key string, value interface{}) {
+	s.Values[key] = value
+	s.modified = true
+}
+
+// Get returns the value stored under key, or nil.
+func (s *Session) Get(key string) interface{} {
+	return s.Values[key]
+}
+
+// Delete removes key from the session.
+func (s *Session) Delete(key string) {
+	if _, ok := s.Values[key]; ok {
+		delete(s.Values, key)
+		s.modified = true
+	}
+}
+
+// Clear removes all values from the session.
+func (s *Session) Clear() {
+	for k := range s.Values {
+		delete(s.Values, k)
+	}
+	s.modified = true
+}
+
+// Destroy removes all values and expires the session cookie.
+func (s *Session) Destroy() {
+	s.Clear()
+	s.Options.MaxAge = -1
+}
+
+// GetString returns the string stored under key. ok is false when the key is
+// missing or the value is not a string.
+func (s *Session) GetString(key string) (v string, ok bool) {
+	v, ok = s.Values[key].(string)
+	return
+}
+
+// GetInt returns the int stored under key. ok is false when the key is missing
+// or the value is not an int.
+func (s *Session) GetInt(key string) (v int, ok bool) {
+	v, ok = s.Values[key].(int)
+	return
+}
+
+// GetInt64 returns the int64 stored under key. ok is false when the key is
+// missing or the value is not an int64.
+func (s *Session) GetInt64(key string) (v int64, ok bool) {
+	v, ok = s.Values[key].(int64)
+	return
+}
+
+// GetFloat64 returns the float64 stored under key. ok is false when the key is
+// missing or the value is not a float64.
+func (s *Session) GetFloat64(key string) (v float64, ok bool) {
+	v, ok = s.Values[key].(float64)
+	return
+}
+
+// GetBool returns the bool stored under key. ok is false when the key is
+// missing or the value is not a bool.
+func (s *Session) GetBool(key string) (v bool, ok bool) {
+	v, ok = s.Values[key].(bool)
+	return
+}
+
+// GetTime returns the time.Time stored under key. ok is false when the key is
+// missing or the value is not a time.Time.
+func (s *Session) GetTime(key string) (v time.Time, ok bool) {
+	v, ok = s.Values[key].(time.Time)
+	return
+}
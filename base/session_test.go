@@ -1,6 +1,13 @@
 package base
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gernest/utron/config"
+	"github.com/gorilla/sessions"
+)
 
 func TestContextSession(t *testing.T) {
 	// should error when the session store is not set
@@ -19,3 +26,72 @@ func TestContextSession(t *testing.T) {
 		t.Error("expected error ", errNoStore)
 	}
 }
+
+func TestDefaultSession(t *testing.T) {
+	ctx := &Context{}
+	_, err := ctx.Session()
+	if err != errNoStore {
+		t.Errorf("expected %v got %v", errNoStore, err)
+	}
+
+	store := sessions.NewCookieStore([]byte("ePAPW9vJv7gHoftvQTyNj5VkWB52mlza"))
+	cfg := &config.Config{SessionName: "_utron"}
+	newCtx := func(r *http.Request) (*Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c := NewContext(w, r)
+		c.Cfg = cfg
+		c.SessionStore = store
+		return c, w
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	ctx, w := newCtx(req)
+	s, err := ctx.Session()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name() != cfg.SessionName {
+		t.Errorf("expected %s got %s", cfg.SessionName, s.Name())
+	}
+	if again, _ := ctx.Session(); again != s {
+		t.Error("expected the session to be cached")
+	}
+	s.Set("name", "gernest")
+	s.Set("visits", 1)
+	ctx.Set(http.StatusCreated)
+	if err = ctx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected %d got %d", http.StatusCreated, w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 cookie got %d", len(cookies))
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.AddCookie(cookies[0])
+	ctx, w = newCtx(req)
+	s, err = ctx.Session()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := s.GetString("name"); !ok || name != "gernest" {
+		t.Errorf("expected gernest got %s", name)
+	}
+	if visits, ok := s.GetInt("visits"); !ok || visits != 1 {
+		t.Errorf("expected 1 got %d", visits)
+	}
+	if _, ok := s.GetBool("name"); ok {
+		t.Error("expected wrong type to not be ok")
+	}
+
+	// unmodified sessions are not saved
+	if err = ctx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("expected no cookie for unmodified session")
+	}
+}
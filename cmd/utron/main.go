@@ -0,0 +1,129 @@
+// Command utron manages utron applications from the command line.
+//
+//	utron migrate [-config dir] up        apply pending migrations
+//	utron migrate [-config dir] down [n]  roll back the last n migrations
+//	utron migrate [-config dir] status    list migrations
+//	utron config [-config dir] check      validate the configuration
+//	utron config [-config dir] dump       print the configuration, secrets redacted
+//	utron config key                      generate a master key
+//	utron config encrypt                  encrypt the secret read from stdin
+//
+// The configuration is read from the config directory by default. Only SQL
+// migrations are known to this command, applications with Go migrations run
+// App.Migrate from their own main function. config check merges the profile
+// named by UTRON_ENV, like App.Init does. config encrypt uses the master key
+// set in UTRON_MASTER_KEY, its output is a value for the secret settings.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gernest/utron/app"
+	"github.com/gernest/utron/config"
+)
+
+const usage = `usage: utron <command> [arguments]
+
+commands:
+  migrate   apply or roll back database migrations
+  config    validate, print or encrypt the configuration
+`
+
+const configUsage = "usage: utron config [-config dir] check|dump|key|encrypt"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "migrate":
+		return migrate(args[1:], stdout, stderr)
+	case "config":
+		return configCommand(args[1:], os.Stdin, stdout, stderr)
+	}
+	return fmt.Errorf("utron: unknown command %s\n%s", args[0], usage)
+}
+
+func migrate(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("config", "config", "the directory of the configuration files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	a := app.NewApp()
+	a.SetConfigPath(*dir)
+	defer a.Close()
+	return a.Migrate(flags.Args(), stdout)
+}
+
+// configCommand checks and prints the configuration, with the profile set by
+// UTRON_ENV, and encrypts secrets.
+func configCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("config", "config", "the directory of the configuration files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(configUsage)
+	}
+	switch flags.Arg(0) {
+	case "check", "dump":
+		cfg, err := config.LoadProfile(*dir, "app", "")
+		if err != nil {
+			return err
+		}
+		if flags.Arg(0) == "dump" {
+			data, err := json.MarshalIndent(cfg.Redacted(), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(stdout, string(data))
+			return nil
+		}
+		if err = app.ValidateConfig(cfg); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", strings.Join(cfg.Files(), ", "))
+		return nil
+	case "key":
+		key, err := config.GenerateMasterKey()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, key)
+		return nil
+	case "encrypt":
+		key, err := config.MasterKey()
+		if err != nil {
+			return err
+		}
+		secret, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		v, err := config.Encrypt(key, strings.TrimRight(string(secret), "\r\n"))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, v)
+		return nil
+	}
+	return errors.New(configUsage)
+}
@@ -1,21 +1,13 @@
 package config
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
 	"reflect"
-	"strconv"
 	"strings"
 
-	"github.com/BurntSushi/toml"
 	"github.com/fatih/camelcase"
 	"github.com/gorilla/securecookie"
-	"github.com/hashicorp/hcl"
-	"gopkg.in/yaml.v2"
 )
 
 var errCfgUnsupported = errors.New("utron: config file format not supported")
@@ -26,13 +18,112 @@ type Config struct {
 	BaseURL      string `json:"base_url" yaml:"base_url" toml:"base_url" hcl:"base_url"`
 	Port         int    `json:"port" yaml:"port" toml:"port" hcl:"port"`
 	Verbose      bool   `json:"verbose" yaml:"verbose" toml:"verbose" hcl:"verbose"`
+
+	// LogLevel is the minimum level of the messages logged by the App, one of
+	// debug, info, warn and error. It defaults to info.
+	LogLevel string `json:"log_level" yaml:"log_level" toml:"log_level" hcl:"log_level"`
+
+	// LogFormat is the format of the messages, text, json or logfmt. It
+	// defaults to text.
+	LogFormat string `json:"log_format" yaml:"log_format" toml:"log_format" hcl:"log_format"`
+
+	// Socket is the path of a unix socket the App listens on instead of the
+	// Port.
+	Socket string `json:"socket" yaml:"socket" toml:"socket" hcl:"socket"`
+
+	// Server timeouts, in seconds. Zero means no timeout. ShutdownTimeout is
+	// the time given to requests in flight to complete when the server stops,
+	// it defaults to 30 seconds.
+	ReadTimeout       int `json:"read_timeout" yaml:"read_timeout" toml:"read_timeout" hcl:"read_timeout"`
+	ReadHeaderTimeout int `json:"read_header_timeout" yaml:"read_header_timeout" toml:"read_header_timeout" hcl:"read_header_timeout"`
+	WriteTimeout      int `json:"write_timeout" yaml:"write_timeout" toml:"write_timeout" hcl:"write_timeout"`
+	IdleTimeout       int `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout" hcl:"idle_timeout"`
+	ShutdownTimeout   int `json:"shutdown_timeout" yaml:"shutdown_timeout" toml:"shutdown_timeout" hcl:"shutdown_timeout"`
+
+	// TLSCert and TLSKey are the paths of the certificate and key files, the
+	// App is served over HTTPS, with HTTP/2, when they are set.
+	TLSCert string `json:"tls_cert" yaml:"tls_cert" toml:"tls_cert" hcl:"tls_cert"`
+	TLSKey  string `json:"tls_key" yaml:"tls_key" toml:"tls_key" hcl:"tls_key"`
+
+	// TLSMinVersion is the minimum TLS version, from 1.0 to 1.3. It defaults to
+	// 1.2.
+	TLSMinVersion string `json:"tls_min_version" yaml:"tls_min_version" toml:"tls_min_version" hcl:"tls_min_version"`
+
+	// TLSClientCA is the path of the certificates of the authorities signing
+	// client certificates, for mutual TLS.
+	TLSClientCA string `json:"tls_client_ca" yaml:"tls_client_ca" toml:"tls_client_ca" hcl:"tls_client_ca"`
+
+	// TLSClientAuth is the client certificate policy
+	// Options are
+	// none, request, require, verify_if_given, require_and_verify. It defaults
+	// to require_and_verify when TLSClientCA is set, and none otherwise.
+	TLSClientAuth string `json:"tls_client_auth" yaml:"tls_client_auth" toml:"tls_client_auth" hcl:"tls_client_auth"`
+
+	// DisableHTTP2 serves HTTPS with HTTP/1.1 only.
+	DisableHTTP2 bool `json:"disable_http2" yaml:"disable_http2" toml:"disable_http2" hcl:"disable_http2"`
+
+	// HTTPRedirectPort is a port listening for plain HTTP requests, which are
+	// redirected to HTTPS. Zero disables the redirect.
+	HTTPRedirectPort int `json:"http_redirect_port" yaml:"http_redirect_port" toml:"http_redirect_port" hcl:"http_redirect_port"`
+
+	// HSTSMaxAge is the max-age, in seconds, of the Strict-Transport-Security
+	// header sent with HTTPS responses. Zero disables the header.
+	HSTSMaxAge            int  `json:"hsts_max_age" yaml:"hsts_max_age" toml:"hsts_max_age" hcl:"hsts_max_age"`
+	HSTSIncludeSubdomains bool `json:"hsts_include_subdomains" yaml:"hsts_include_subdomains" toml:"hsts_include_subdomains" hcl:"hsts_include_subdomains"`
+
+	// Embedded loads the views and static files from the file system set on
+	// the App, e.g. an embed.FS, instead of the disk. ViewsDir and StaticDir
+	// are paths inside it. This allows single binary deployments.
+	Embedded bool `json:"embedded" yaml:"embedded" toml:"embedded" hcl:"embedded"`
+
+	// Dev enables development mode. Templates are reloaded when they change,
+	// and errors are shown in the browser.
+	Dev bool `json:"dev" yaml:"dev" toml:"dev" hcl:"dev"`
+
 	StaticDir    string `json:"static_dir" yaml:"static_dir" toml:"static_dir" hcl:"static_dir"`
 	ViewsDir     string `json:"view_dir" yaml:"view_dir" toml:"view_dir" hcl:"view_dir"`
+
+	// ViewEngines maps template file extensions to template engines, e.g.
+	// ".tpl" = "text". Engines are html, text and mustache. Extensions which
+	// are not listed use the default engines.
+	ViewEngines map[string]string `json:"view_engines" yaml:"view_engines" toml:"view_engines" hcl:"view_engines"`
+
 	Database     string `json:"database" yaml:"database" toml:"database" hcl:"database"`
-	DatabaseConn string `json:"database_conn" yaml:"database_conn" toml:"database_conn" hcl:"database_conn"`
+	DatabaseConn string `json:"database_conn" yaml:"database_conn" toml:"database_conn" hcl:"database_conn" secret:"conn"`
+
+	// DatabaseReplicas are connection strings of read replicas of the
+	// Database. Queries are sent to the replicas in turn, everything else goes
+	// to the primary database.
+	DatabaseReplicas []string `json:"database_replicas" yaml:"database_replicas" toml:"database_replicas" hcl:"database_replicas" secret:"conn"`
+
+	// Connection pool limits, the lifetimes are in seconds. Zero keeps the
+	// database/sql defaults.
+	DatabaseMaxOpenConns    int `json:"database_max_open_conns" yaml:"database_max_open_conns" toml:"database_max_open_conns" hcl:"database_max_open_conns"`
+	DatabaseMaxIdleConns    int `json:"database_max_idle_conns" yaml:"database_max_idle_conns" toml:"database_max_idle_conns" hcl:"database_max_idle_conns"`
+	DatabaseConnMaxLifetime int `json:"database_conn_max_lifetime" yaml:"database_conn_max_lifetime" toml:"database_conn_max_lifetime" hcl:"database_conn_max_lifetime"`
+	DatabaseConnMaxIdleTime int `json:"database_conn_max_idle_time" yaml:"database_conn_max_idle_time" toml:"database_conn_max_idle_time" hcl:"database_conn_max_idle_time"`
+
+	// Databases are additional named databases, e.g. for analytics. They are
+	// available as Ctx.DBs[name].
+	Databases map[string]DatabaseConfig `json:"databases" yaml:"databases" toml:"databases" hcl:"databases"`
+
 	Automigrate  bool   `json:"automigrate" yaml:"automigrate" toml:"automigrate" hcl:"automigrate"`
 	NoModel      bool   `json:"no_model" yaml:"no_model" toml:"no_model" hcl:"no_model"`
 
+	// TransactionPerRequest runs every request in a database transaction,
+	// which is committed only when the response is successful.
+	TransactionPerRequest bool `json:"transaction_per_request" yaml:"transaction_per_request" toml:"transaction_per_request" hcl:"transaction_per_request"`
+
+	// MigrationsDir is the directory holding SQL migrations. Pending migrations
+	// are applied on startup when AutoMigrateVersions is true.
+	MigrationsDir string `json:"migrations_dir" yaml:"migrations_dir" toml:"migrations_dir" hcl:"migrations_dir"`
+
+	// AutoMigrateVersions applies the pending versioned migrations, Go and SQL,
+	// on startup. It is off by default, as migrations may drop columns or
+	// change data, they are then run with utron migrate or App.Migrate.
+	// Automigrate only creates the tables of the registered models.
+	AutoMigrateVersions bool `json:"auto_migrate_versions" yaml:"auto_migrate_versions" toml:"auto_migrate_versions" hcl:"auto_migrate_versions"`
+
 	// session
 	SessionName     string `json:"session_name" yaml:"session_name" toml:"session_name" hcl:"session_name"`
 	SessionPath     string `json:"session_path" yaml:"session_path" toml:"session_path" hcl:"session_path"`
@@ -41,6 +132,20 @@ type Config struct {
 	SessionSecure   bool   `json:"session_secure" yaml:"session_secure" toml:"session_secure" hcl:"session_secure"`
 	SessionHTTPOnly bool   `json:"session_httponly" yaml:"session_httponly" toml:"session_httponly" hcl:"session_httponly"`
 
+	// SessionSameSite sets the SameSite attribute of the session cookie.
+	// Options are
+	// lax, strict, none. When empty the browser default is used.
+	SessionSameSite string `json:"session_same_site" yaml:"session_same_site" toml:"session_same_site" hcl:"session_same_site"`
+
+	// SessionDir is the directory used by the file session store. It defaults
+	// to the system temporary directory.
+	SessionDir string `json:"session_dir" yaml:"session_dir" toml:"session_dir" hcl:"session_dir"`
+
+	// SessionCleanupInterval is the number of seconds between runs of the
+	// background job that purges expired sessions from server side stores.
+	// A negative value disables the cleanup.
+	SessionCleanupInterval int `json:"session_cleanup_interval" yaml:"session_cleanup_interval" toml:"session_cleanup_interval" hcl:"session_cleanup_interval"`
+
 	// The name of the session store to use
 	// Options are
 	// file , cookie ,ql
@@ -50,10 +155,91 @@ type Config struct {
 	Flash string `json:"flash" yaml:"flash" toml:"flash" hcl:"flash"`
 
 	// KeyPair for secure cookie its a comma separates strings of keys.
-	SessionKeyPair []string `json:"session_key_pair" yaml:"session_key_pair" toml:"session_key_pair" hcl:"session_key_pair"`
+	// Random keys are generated when it is not set, sessions are then lost
+	// when the App restarts.
+	//
+	// Keys come in pairs of authentication and encryption key. The first pair
+	// is used to encode session cookies, and all pairs are used to decode them.
+	// To rotate keys, add the new pair in front and keep the old pair until
+	// sessions created with it have expired.
+	SessionKeyPair []string `json:"session_key_pair" yaml:"session_key_pair" toml:"session_key_pair" hcl:"session_key_pair" secret:"true"`
 
-	// flash message
+	// FlashContextKey is the key in the context data holding flash messages
+	// from previous requests when a template is rendered.
 	FlashContextKey string `json:"flash_context_key" yaml:"flash_context_key" toml:"flash_context_key" hcl:"flash_context_key"`
+
+	// LocalesDir is the directory holding the message catalogs, one file per
+	// locale. Translations are disabled when it does not exist.
+	LocalesDir string `json:"locales_dir" yaml:"locales_dir" toml:"locales_dir" hcl:"locales_dir"`
+
+	// DefaultLocale is the locale used when none is detected for a request.
+	DefaultLocale string `json:"default_locale" yaml:"default_locale" toml:"default_locale" hcl:"default_locale"`
+
+	// LocaleCookie is the name of the cookie, and the session value, holding
+	// the locale chosen by the user.
+	LocaleCookie string `json:"locale_cookie" yaml:"locale_cookie" toml:"locale_cookie" hcl:"locale_cookie"`
+
+	// LocaleURLPrefix enables locales in the URL prefix, e.g. /fr/products is
+	// routed as /products with the locale fr.
+	LocaleURLPrefix bool `json:"locale_url_prefix" yaml:"locale_url_prefix" toml:"locale_url_prefix" hcl:"locale_url_prefix"`
+
+	// ReloadInterval is the number of seconds between checks of the
+	// configuration files for changes, the configuration is then reloaded
+	// without restarting. It is also reloaded on SIGHUP when it is set, a
+	// negative value reloads on SIGHUP only. Zero disables reloads.
+	ReloadInterval int `json:"reload_interval" yaml:"reload_interval" toml:"reload_interval" hcl:"reload_interval"`
+
+	// EnvPrefix is prepended to the names of the environment variables
+	// overriding the settings, e.g. UTRON_ gives UTRON_PORT. See SyncEnv.
+	EnvPrefix string `json:"env_prefix" yaml:"env_prefix" toml:"env_prefix" hcl:"env_prefix" env:"-"`
+
+	// sections are all the settings read from the configuration files,
+	// including the application sections unknown to Config. See Decode.
+	sections map[string]interface{}
+
+	// sources maps the keys of the settings to the file setting them.
+	sources map[string]string
+	files   []string
+
+	// keysGenerated is true when the SessionKeyPair was generated because it
+	// is not set.
+	keysGenerated bool
+}
+
+// DatabaseConfig are the settings of a database connection.
+type DatabaseConfig struct {
+	Database        string   `json:"database" yaml:"database" toml:"database" hcl:"database"`
+	DatabaseConn    string   `json:"database_conn" yaml:"database_conn" toml:"database_conn" hcl:"database_conn" secret:"conn"`
+	Replicas        []string `json:"replicas" yaml:"replicas" toml:"replicas" hcl:"replicas" secret:"conn"`
+	MaxOpenConns    int      `json:"max_open_conns" yaml:"max_open_conns" toml:"max_open_conns" hcl:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns" yaml:"max_idle_conns" toml:"max_idle_conns" hcl:"max_idle_conns"`
+	ConnMaxLifetime int      `json:"conn_max_lifetime" yaml:"conn_max_lifetime" toml:"conn_max_lifetime" hcl:"conn_max_lifetime"`
+	ConnMaxIdleTime int      `json:"conn_max_idle_time" yaml:"conn_max_idle_time" toml:"conn_max_idle_time" hcl:"conn_max_idle_time"`
+}
+
+// DefaultPort is the port of the server when Port is not set.
+const DefaultPort = 8090
+
+// ServerPort returns the port the server listens on, Port or DefaultPort when
+// it is not set.
+func (c *Config) ServerPort() int {
+	if c.Port == 0 {
+		return DefaultPort
+	}
+	return c.Port
+}
+
+// DatabaseConfig returns the settings of the default database.
+func (c *Config) DatabaseConfig() DatabaseConfig {
+	return DatabaseConfig{
+		Database:        c.Database,
+		DatabaseConn:    c.DatabaseConn,
+		Replicas:        c.DatabaseReplicas,
+		MaxOpenConns:    c.DatabaseMaxOpenConns,
+		MaxIdleConns:    c.DatabaseMaxIdleConns,
+		ConnMaxLifetime: c.DatabaseConnMaxLifetime,
+		ConnMaxIdleTime: c.DatabaseConnMaxIdleTime,
+	}
 }
 
 // DefaultConfig returns the default configuration settings.
@@ -61,20 +247,29 @@ func DefaultConfig() *Config {
 	a := securecookie.GenerateRandomKey(32)
 	b := securecookie.GenerateRandomKey(32)
 	return &Config{
-		AppName:       "utron web app",
-		BaseURL:       "http://localhost:8090",
-		Port:          8090,
-		Verbose:       false,
-		StaticDir:     "static",
-		ViewsDir:      "views",
-		Automigrate:   true,
-		SessionName:   "_utron",
-		SessionPath:   "/",
-		SessionMaxAge: 2592000,
+		AppName:                "utron web app",
+		BaseURL:                "http://localhost:8090",
+		Port:                   DefaultPort,
+		Verbose:                false,
+		ShutdownTimeout:        30,
+		StaticDir:              "static",
+		ViewsDir:               "views",
+		Automigrate:            true,
+		MigrationsDir:          "migrations",
+		SessionName:            "_utron",
+		SessionPath:            "/",
+		SessionMaxAge:          2592000,
+		SessionHTTPOnly:        true,
+		SessionStore:           "ql",
+		SessionCleanupInterval: 300,
 		SessionKeyPair: []string{
 			string(a), string(b),
 		},
-		Flash: "_flash",
+		Flash:           "_flash",
+		FlashContextKey: "Flash",
+		LocalesDir:      "locales",
+		DefaultLocale:   "en",
+		LocaleCookie:    "locale",
 	}
 }
 
@@ -88,94 +283,24 @@ func NewConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	cfg := &Config{}
-	switch filepath.Ext(path) {
-	case ".json":
-		jerr := json.Unmarshal(data, cfg)
-		if jerr != nil {
-			return nil, jerr
-		}
-	case ".toml":
-		_, terr := toml.Decode(string(data), cfg)
-		if terr != nil {
-			return nil, terr
-		}
-	case ".yml":
-		yerr := yaml.Unmarshal(data, cfg)
-		if yerr != nil {
-			return nil, yerr
-		}
-	case ".hcl":
-		obj, herr := hcl.Parse(string(data))
-		if herr != nil {
-			return nil, herr
-		}
-		if herr = hcl.DecodeObject(&cfg, obj); herr != nil {
-			return nil, herr
-		}
-	default:
-		return nil, errCfgUnsupported
-	}
-	err = cfg.SyncEnv()
-	if err != nil {
-		return nil, err
-	}
-
-	// ensure the key pairs are set
-	if cfg.SessionKeyPair == nil {
-		a := securecookie.GenerateRandomKey(32)
-		b := securecookie.GenerateRandomKey(32)
-		cfg.SessionKeyPair = []string{
-			string(a), string(b),
-		}
-	}
-	return cfg, nil
+	return Load(path)
 }
 
 // SyncEnv overrides c field's values that are set in the environment.
 //
 // The environment variable names are derived from config fields by underscoring, and uppercasing
-// the name. E.g. AppName will have a corresponding environment variable APP_NAME
+// the name. E.g. AppName will have a corresponding environment variable APP_NAME. The name can be
+// set with an env struct tag, and env:"-" ignores the field.
+//
+// When EnvPrefix is set, it is prepended to all the names, e.g. UTRON_APP_NAME for the prefix
+// UTRON_. This avoids collisions with variables such as PORT set for other software.
 //
-// NOTE only int, string and bool fields are supported and the corresponding values are set.
-// when the field value is not supported it is ignored.
+// Strings, booleans, integers, floats and time.Duration are supported. Slices are comma
+// separated, e.g. SESSION_KEY_PAIR=auth,encrypt. Fields of nested structs are named after the
+// struct field, and the entries of maps of structs after the key, e.g.
+// DATABASES_ANALYTICS_DATABASE_CONN. Empty variables and unsupported fields are ignored.
 func (c *Config) SyncEnv() error {
-	cfg := reflect.ValueOf(c).Elem()
-	cTyp := cfg.Type()
-
-	for k := range make([]struct{}, cTyp.NumField()) {
-		field := cTyp.Field(k)
-
-		cm := getEnvName(field.Name)
-		env := os.Getenv(cm)
-		if env == "" {
-			continue
-		}
-		switch field.Type.Kind() {
-		case reflect.String:
-			cfg.FieldByName(field.Name).SetString(env)
-		case reflect.Int:
-			v, err := strconv.Atoi(env)
-			if err != nil {
-				return fmt.Errorf("utron: loading config field %s %v", field.Name, err)
-			}
-			cfg.FieldByName(field.Name).Set(reflect.ValueOf(v))
-		case reflect.Bool:
-			b, err := strconv.ParseBool(env)
-			if err != nil {
-				return fmt.Errorf("utron: loading config field %s %v", field.Name, err)
-			}
-			cfg.FieldByName(field.Name).SetBool(b)
-		}
-
-	}
-	return nil
+	return syncEnv(reflect.ValueOf(c).Elem(), c.EnvPrefix)
 }
 
 // getEnvName returns all upper case and underscore separated string, from field.
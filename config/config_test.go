@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -95,3 +96,34 @@ func TestConfigEnv(t *testing.T) {
 		t.Errorf("expected utron got %s", cfg.AppName)
 	}
 }
+
+func TestDatabases(t *testing.T) {
+	// TestConfigEnv leaves these set.
+	t.Setenv("DATABASE", "")
+	t.Setenv("DATABASE_CONN", "")
+
+	expect := DatabaseConfig{
+		Database:        "postgres",
+		DatabaseConn:    "postgres://localhost/app",
+		Replicas:        []string{"postgres://replica1/app", "postgres://replica2/app"},
+		MaxOpenConns:    20,
+		ConnMaxLifetime: 300,
+	}
+	analytics := DatabaseConfig{
+		Database:     "mysql",
+		DatabaseConn: "root@/analytics",
+		MaxOpenConns: 5,
+	}
+	for _, ext := range []string{".json", ".toml", ".yml", ".hcl"} {
+		cfg, err := NewConfig("../fixtures/databases/app" + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dc := cfg.DatabaseConfig(); !reflect.DeepEqual(dc, expect) {
+			t.Errorf("%s: expected %v got %v", ext, expect, dc)
+		}
+		if dc := cfg.Databases["analytics"]; !reflect.DeepEqual(dc, analytics) {
+			t.Errorf("%s: expected %v got %v", ext, analytics, dc)
+		}
+	}
+}
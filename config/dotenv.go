@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadDotEnv sets the environment variables defined in the .env files, which
+// are read in order. Variables which are already set in the environment are
+// not changed, and missing files are ignored.
+//
+// Each line is KEY=value, optionally prefixed by export. Empty lines and lines
+// starting with # are skipped. Values can be quoted, double quoted values
+// support escapes such as \n.
+func LoadDotEnv(files ...string) error {
+	for _, file := range files {
+		vars, err := readDotEnv(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		for _, kv := range vars {
+			if _, ok := os.LookupEnv(kv[0]); ok {
+				continue
+			}
+			if err = os.Setenv(kv[0], kv[1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readDotEnv returns the key value pairs of the .env file, in order.
+func readDotEnv(file string) ([][2]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vars [][2]string
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("utron: %s:%d expected KEY=value", file, n)
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		value, err = dotEnvValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("utron: %s:%d %v", file, n, err)
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+	return vars, s.Err()
+}
+
+// dotEnvValue returns the value v, unquoted, without a trailing comment.
+func dotEnvValue(v string) (string, error) {
+	if v == "" {
+		return v, nil
+	}
+	q := v[0]
+	if q != '"' && q != '\'' {
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		return v, nil
+	}
+	end := -1
+	for i := 1; i < len(v); i++ {
+		if v[i] == '\\' && q == '"' {
+			i++
+			continue
+		}
+		if v[i] == q {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return "", errors.New("unterminated quoted value")
+	}
+	if rest := strings.TrimSpace(v[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after the quoted value", rest)
+	}
+	if q == '\'' {
+		return v[1:end], nil
+	}
+	return strconv.Unquote(v[:end+1])
+}
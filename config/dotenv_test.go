@@ -0,0 +1,43 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDotEnv(t *testing.T) {
+	t.Setenv("DB_USER", "admin")
+	for _, k := range []string{"DATA_DIR", "EMPTY", "QUOTED"} {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+	if err := LoadDotEnv("../fixtures/profiles/.env", "../fixtures/nothing/.env"); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{
+		"DB_USER":  "admin",
+		"DATA_DIR": "/var/lib/utron",
+		"EMPTY":    "",
+		"QUOTED":   "a # b",
+	}
+	for k, v := range expect {
+		if got, ok := os.LookupEnv(k); !ok || got != v {
+			t.Errorf("%s: expected %q got %q", k, v, got)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "utron-dotenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bad := filepath.Join(dir, ".env")
+	if err = ioutil.WriteFile(bad, []byte("# comment\nNOVALUE\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = LoadDotEnv(bad); err == nil || err.Error() != "utron: "+bad+":2 expected KEY=value" {
+		t.Errorf("expected the line of the error got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// syncEnv sets the fields of the struct v from the environment variables named
+// after the fields, prefixed by prefix.
+func syncEnv(v reflect.Value, prefix string) error {
+	_, err := syncStruct(v, prefix)
+	return err
+}
+
+// syncStruct is syncEnv, it returns true when a field was set.
+func syncStruct(v reflect.Value, prefix string) (bool, error) {
+	typ := v.Type()
+	set := false
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := getEnvName(field.Name)
+		if tag, ok := field.Tag.Lookup("env"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		ok, err := syncValue(v.Field(i), prefix+name)
+		if err != nil {
+			return false, fmt.Errorf("utron: loading config field %s %v", field.Name, err)
+		}
+		set = set || ok
+	}
+	return set, nil
+}
+
+// syncValue sets v from the environment variable name, or from the variables
+// prefixed by name for structs and maps of structs.
+func syncValue(v reflect.Value, name string) (bool, error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		return syncStruct(v, name+"_")
+	case reflect.Ptr:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			break
+		}
+		e := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			e.Elem().Set(v.Elem())
+		}
+		ok, err := syncStruct(e.Elem(), name+"_")
+		if ok {
+			v.Set(e)
+		}
+		return ok, err
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.Struct {
+			return false, nil
+		}
+		set := false
+		for _, key := range v.MapKeys() {
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(key))
+			ok, err := syncStruct(e, name+"_"+envKey(key.String())+"_")
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				v.SetMapIndex(key, e)
+				set = true
+			}
+		}
+		return set, nil
+	}
+	env := os.Getenv(name)
+	if env == "" {
+		return false, nil
+	}
+	if v.Kind() == reflect.Slice {
+		parts := strings.Split(env, ",")
+		s := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			ok, err := setScalar(s.Index(i), strings.TrimSpace(p))
+			if !ok || err != nil {
+				return false, err
+			}
+		}
+		v.Set(s)
+		return true, nil
+	}
+	return setScalar(v, env)
+}
+
+// setScalar sets v to the value s. It returns false when the kind of v is not
+// supported.
+func setScalar(v reflect.Value, s string) (bool, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return false, err
+		}
+		v.SetInt(int64(d))
+		return true, nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return false, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return false, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return false, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return false, err
+		}
+		v.SetFloat(f)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// envKey returns the map key k as it appears in environment variable names.
+func envKey(k string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(k))
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type limits struct {
+	Rate    float64
+	Timeout time.Duration
+	Hosts   []string
+	Ports   []int
+	Burst   uint8
+	Secret  string `env:"LIMITS_TOKEN"`
+	Ignored string `env:"-"`
+	Stripe  struct {
+		Currency string
+	}
+	Backup *struct {
+		Region string
+	}
+}
+
+func TestSyncEnv(t *testing.T) {
+	env := map[string]string{
+		"RATE":            "1.5",
+		"TIMEOUT":         "1m30s",
+		"HOSTS":           "a.example.com, b.example.com",
+		"PORTS":           "80,443",
+		"BURST":           "20",
+		"LIMITS_TOKEN":    "token",
+		"IGNORED":         "set",
+		"STRIPE_CURRENCY": "eur",
+		"BACKUP_REGION":   "eu",
+	}
+	for k, v := range env {
+		t.Setenv("TEST_"+k, v)
+	}
+	var l limits
+	if err := syncEnv(reflect.ValueOf(&l).Elem(), "TEST_"); err != nil {
+		t.Fatal(err)
+	}
+	if l.Rate != 1.5 || l.Timeout != 90*time.Second || l.Burst != 20 || l.Secret != "token" || l.Ignored != "" {
+		t.Errorf("unexpected values %+v", l)
+	}
+	if !reflect.DeepEqual(l.Hosts, []string{"a.example.com", "b.example.com"}) || !reflect.DeepEqual(l.Ports, []int{80, 443}) {
+		t.Errorf("unexpected slices %v %v", l.Hosts, l.Ports)
+	}
+	if l.Stripe.Currency != "eur" || l.Backup == nil || l.Backup.Region != "eu" {
+		t.Errorf("unexpected nested structs %+v", l)
+	}
+
+	// pointers are left nil when nothing is set.
+	var other limits
+	if err := syncEnv(reflect.ValueOf(&other).Elem(), "OTHER_"); err != nil || other.Backup != nil {
+		t.Errorf("expected no changes got %+v %v", other, err)
+	}
+
+	for _, bad := range []string{"TEST_TIMEOUT=90", "TEST_BURST=300", "TEST_PORTS=80,http", "TEST_RATE=fast"} {
+		t.Run(bad, func(t *testing.T) {
+			kv := strings.SplitN(bad, "=", 2)
+			t.Setenv(kv[0], kv[1])
+			if err := syncEnv(reflect.ValueOf(&limits{}).Elem(), "TEST_"); err == nil {
+				t.Errorf("expected an error for %s", bad)
+			}
+		})
+	}
+}
+
+func TestSyncEnvPrefix(t *testing.T) {
+	t.Setenv("PORT", "3000")
+	t.Setenv("UTRON_PORT", "9000")
+	t.Setenv("UTRON_SESSION_KEY_PAIR", "auth,encrypt")
+	t.Setenv("UTRON_DATABASES_ANALYTICS_MAX_OPEN_CONNS", "7")
+	t.Setenv("UTRON_ENV_PREFIX", "OTHER_")
+	cfg := &Config{
+		Port:      8090,
+		EnvPrefix: "UTRON_",
+		Databases: map[string]DatabaseConfig{"analytics": {Database: "mysql"}},
+	}
+	if err := cfg.SyncEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9000 || cfg.EnvPrefix != "UTRON_" {
+		t.Errorf("expected the prefixed variables only got %d %s", cfg.Port, cfg.EnvPrefix)
+	}
+	if !reflect.DeepEqual(cfg.SessionKeyPair, []string{"auth", "encrypt"}) {
+		t.Errorf("expected the key pair got %v", cfg.SessionKeyPair)
+	}
+	if db := cfg.Databases["analytics"]; db.Database != "mysql" || db.MaxOpenConns != 7 {
+		t.Errorf("expected the database to be updated got %+v", db)
+	}
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gorilla/securecookie"
+	"github.com/hashicorp/hcl"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvVar is the environment variable selecting the configuration profile,
+// e.g. production or test.
+const EnvVar = "UTRON_ENV"
+
+// Extensions are the supported configuration file extensions, in the order
+// they are searched.
+var Extensions = []string{".json", ".toml", ".yml", ".hcl"}
+
+// Load reads the configuration files in order and deep merges them: settings
+// of later files override the ones of earlier files, and sections such as
+// databases are merged key by key. The files can be in different formats.
+//
+// References to environment variables, ${VAR} or ${VAR:-default}, are
+// replaced in the string values of the files after they are decoded, so the
+// values are never parsed as part of the file. Settings which are not strings,
+// e.g. port, are set from the environment by SyncEnv, which is applied next. The file: and enc:
+// references of the secret settings are then resolved, see Encrypt.
+func Load(files ...string) (*Config, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("utron: no configuration file")
+	}
+	merged := make(map[string]interface{})
+	sources := make(map[string]string)
+	for _, file := range files {
+		m, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		setSources(sources, "", m, file)
+		merge(merged, m)
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	if err = json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("utron: decoding %v %v", files, err)
+	}
+	cfg.sections = merged
+	cfg.sources = sources
+	cfg.files = files
+	if err = cfg.SyncEnv(); err != nil {
+		return nil, err
+	}
+	if err = resolveSecrets(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+
+	// ensure the key pairs are set
+	if cfg.SessionKeyPair == nil {
+		a := securecookie.GenerateRandomKey(32)
+		b := securecookie.GenerateRandomKey(32)
+		cfg.SessionKeyPair = []string{
+			string(a), string(b),
+		}
+		cfg.keysGenerated = true
+	}
+	return cfg, nil
+}
+
+// LoadProfile loads the configuration file name in dir, e.g. app.toml,
+// followed by the file of the profile env when it exists, e.g.
+// app.production.toml. When env is empty, the value of UTRON_ENV is used. It
+// is an error when the profile has no file.
+//
+// The .env files in the working directory and in dir are loaded first, see
+// LoadDotEnv, so they can set UTRON_ENV.
+func LoadProfile(dir, name, env string) (*Config, error) {
+	if err := LoadDotEnv(".env", filepath.Join(dir, ".env")); err != nil {
+		return nil, err
+	}
+	file, err := FindFile(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{file}
+	if env == "" {
+		env = os.Getenv(EnvVar)
+	}
+	if env != "" {
+		if file, err = FindFile(dir, name+"."+env); err != nil {
+			return nil, fmt.Errorf("utron: no configuration file for the profile %s, %v", env, err)
+		}
+		files = append(files, file)
+	}
+	return Load(files...)
+}
+
+// FindFile finds the configuration file name in the directory dir, either
+// without extension or with one of the Extensions.
+func FindFile(dir, name string) (file string, err error) {
+	for _, ext := range Extensions {
+		file = filepath.Join(dir, name)
+		if info, serr := os.Stat(file); serr == nil && !info.IsDir() {
+			return
+		}
+		file = file + ext
+		if info, serr := os.Stat(file); serr == nil && !info.IsDir() {
+			return
+		}
+	}
+	return "", fmt.Errorf("utron: can't find configuration file %s in %s", name, dir)
+}
+
+// readFile decodes the configuration file path into a map, and interpolates
+// the environment variables in its string values.
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(data, &m)
+	case ".toml":
+		_, err = toml.Decode(string(data), &m)
+	case ".yml":
+		var y map[interface{}]interface{}
+		if err = yaml.Unmarshal(data, &y); err == nil {
+			m = normalize(y).(map[string]interface{})
+		}
+	case ".hcl":
+		var h map[string]interface{}
+		if err = hcl.Unmarshal(data, &h); err == nil {
+			m = normalize(h).(map[string]interface{})
+		}
+	default:
+		return nil, errCfgUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return interpolate(m).(map[string]interface{}), nil
+}
+
+// interpolate expands the string values of v, a decoded configuration, and of
+// its nested maps and lists.
+func interpolate(v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		return expand(x)
+	case map[string]interface{}:
+		for k, e := range x {
+			x[k] = interpolate(e)
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = interpolate(e)
+		}
+	case []map[string]interface{}:
+		for _, e := range x {
+			interpolate(e)
+		}
+	}
+	return v
+}
+
+// expand replaces the references to environment variables in s. ${VAR} is
+// replaced by the value of VAR, and ${VAR:-default} by default when VAR is
+// empty or not set. The default can contain balanced braces, and \} for a
+// closing brace. A leading $ escapes a reference, $${VAR} is left as ${VAR}.
+//
+// The values are not parsed, they can contain quotes or newlines.
+func expand(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		value, n, ok := expandRef(s[i+2:])
+		if !ok {
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(value)
+		s = s[i+2+n:]
+	}
+}
+
+// expandRef expands the reference at the start of s, which follows ${. It
+// returns the value and the length of the reference, or false when s does not
+// start with a valid reference.
+func expandRef(s string) (value string, n int, ok bool) {
+	for n < len(s) && (s[n] == '_' || 'A' <= s[n] && s[n] <= 'Z' || 'a' <= s[n] && s[n] <= 'z' || n > 0 && '0' <= s[n] && s[n] <= '9') {
+		n++
+	}
+	if n == 0 || n == len(s) {
+		return "", 0, false
+	}
+	name := s[:n]
+	var def strings.Builder
+	switch {
+	case s[n] == '}':
+		n++
+	case strings.HasPrefix(s[n:], ":-"):
+		depth := 0
+		closed := false
+		for n += 2; n < len(s) && !closed; n++ {
+			switch c := s[n]; {
+			case c == '\\' && n+1 < len(s):
+				n++
+				def.WriteByte(s[n])
+			case c == '{':
+				depth++
+				def.WriteByte(c)
+			case c == '}' && depth == 0:
+				closed = true
+			case c == '}':
+				depth--
+				def.WriteByte(c)
+			default:
+				def.WriteByte(c)
+			}
+		}
+		if !closed {
+			return "", 0, false
+		}
+	default:
+		return "", 0, false
+	}
+	if v := os.Getenv(name); v != "" {
+		return v, n, true
+	}
+	return def.String(), n, true
+}
+
+// normalize converts the maps decoded from yaml, which have interface{} keys,
+// and the blocks decoded from hcl, which are lists of maps, to
+// map[string]interface{}.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[k] = normalize(e)
+		}
+		return m
+	case []map[string]interface{}:
+		m := make(map[string]interface{})
+		for _, e := range x {
+			merge(m, normalize(e).(map[string]interface{}))
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(x))
+		for i, e := range x {
+			l[i] = normalize(e)
+		}
+		return l
+	}
+	return v
+}
+
+// setSources records file as the source of the keys of m, nested keys are
+// joined with dots.
+func setSources(sources map[string]string, prefix string, m map[string]interface{}, file string) {
+	for k, v := range m {
+		sources[prefix+k] = file
+		if sm, ok := v.(map[string]interface{}); ok {
+			setSources(sources, prefix+k+".", sm, file)
+		}
+	}
+}
+
+// merge merges src into dst. Maps are merged recursively, other values of src
+// replace the ones of dst.
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		sm, ok := v.(map[string]interface{})
+		if dm, dok := dst[k].(map[string]interface{}); ok && dok {
+			merge(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	// TestConfigEnv leaves these set.
+	t.Setenv("DATABASE", "")
+	t.Setenv("DATABASE_CONN", "")
+	t.Setenv("PORT", "")
+	t.Setenv("APP_NAME", "")
+	t.Setenv(EnvVar, "")
+	t.Setenv("DB_USER", "")
+	t.Setenv("DATA_DIR", "")
+	os.Unsetenv("DB_USER")
+	os.Unsetenv("DATA_DIR")
+
+	cfg, err := LoadProfile("../fixtures/profiles", "app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8090 || cfg.Database != "sqlite3" {
+		t.Errorf("expected the base settings got %d %s", cfg.Port, cfg.Database)
+	}
+	if cfg.DatabaseConn != "/var/lib/utron/app.db" {
+		t.Errorf("expected the variable of the .env file got %s", cfg.DatabaseConn)
+	}
+	if cfg.SessionName != "${literal}" {
+		t.Errorf("expected an escaped reference got %s", cfg.SessionName)
+	}
+
+	t.Setenv(EnvVar, "production")
+	cfg, err = LoadProfile("../fixtures/profiles", "app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 80 || cfg.AppName != "utron web app" {
+		t.Errorf("expected the profile merged over the base got %d %s", cfg.Port, cfg.AppName)
+	}
+	if cfg.DatabaseConn != "postgres://utron@localhost/app" {
+		t.Errorf("expected interpolation got %s", cfg.DatabaseConn)
+	}
+	db := cfg.Databases["analytics"]
+	if db.Database != "sqlite3" || db.MaxOpenConns != 5 || db.DatabaseConn != "/var/lib/analytics.db" {
+		t.Errorf("expected the databases deep merged got %+v", db)
+	}
+
+	// profiles without a file are an error.
+	if _, err = LoadProfile("../fixtures/profiles", "app", "staging"); err == nil {
+		t.Error("expected an error for a profile without a file")
+	}
+
+	// the values are not parsed, they can't change other settings.
+	t.Setenv("DB_USER", "x\"\nport: 1\n#}")
+	cfg, err = LoadProfile("../fixtures/profiles", "app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 80 || cfg.DatabaseConn != "postgres://x\"\nport: 1\n#}@localhost/app" {
+		t.Errorf("expected the value to be kept as is got %d %q", cfg.Port, cfg.DatabaseConn)
+	}
+
+	// the environment wins over the .env file and the files.
+	t.Setenv(EnvVar, "")
+	t.Setenv("DATA_DIR", "/data")
+	t.Setenv("PORT", "9000")
+	cfg, err = LoadProfile("../fixtures/profiles", "app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DatabaseConn != "/data/app.db" || cfg.Port != 9000 {
+		t.Errorf("expected the environment to win got %s %d", cfg.DatabaseConn, cfg.Port)
+	}
+
+	if _, err = LoadProfile("../fixtures/nothing", "app", ""); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	t.Setenv("EXPAND_SET", "value")
+	t.Setenv("EXPAND_EMPTY", "")
+	sample := []struct {
+		in, out string
+	}{
+		{"${EXPAND_SET}", "value"},
+		{"a ${EXPAND_SET} b", "a value b"},
+		{"${EXPAND_EMPTY:-default}", "default"},
+		{"${EXPAND_UNSET:-{a}b}", "{a}b"},
+		{"${EXPAND_UNSET:-a\\}b}", "a}b"},
+		{"${EXPAND_UNSET}", ""},
+		{"$${EXPAND_SET}", "${EXPAND_SET}"},
+		{"${EXPAND_SET", "${EXPAND_SET"},
+		{"${1X}", "${1X}"},
+		{"$$", "$$"},
+	}
+	for _, v := range sample {
+		if got := expand(v.in); got != v.out {
+			t.Errorf("%s: expected %q got %q", v.in, v.out, got)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dst := map[string]interface{}{
+		"a": 1,
+		"m": map[string]interface{}{"x": 1, "y": 2},
+		"l": []interface{}{1, 2},
+	}
+	merge(dst, map[string]interface{}{
+		"m": map[string]interface{}{"y": 3},
+		"l": []interface{}{3},
+	})
+	m := dst["m"].(map[string]interface{})
+	if dst["a"] != 1 || m["x"] != 1 || m["y"] != 3 || len(dst["l"].([]interface{})) != 1 {
+		t.Errorf("unexpected merge %v", dst)
+	}
+}
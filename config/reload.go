@@ -0,0 +1,52 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// liveKeys are the settings which take effect for new requests when the
+// configuration is reloaded. The other settings are read when the App starts,
+// changing them requires a restart. Application sections are always reloaded.
+var liveKeys = map[string]bool{
+	"app_name":                true,
+	"base_url":                true,
+	"verbose":                 true,
+	"log_level":               true,
+	"hsts_max_age":            true,
+	"hsts_include_subdomains": true,
+	"flash":                   true,
+	"flash_context_key":       true,
+	"session_name":            true,
+	"locale_cookie":           true,
+	"locale_url_prefix":       true,
+}
+
+// Reloaded returns the configuration to use after next was loaded to replace
+// c. It is next, with the settings which require a restart kept from c, and
+// the keys of those settings which changed, so they can be reported.
+func (c *Config) Reloaded(next *Config) (*Config, []string) {
+	cfg := *next
+	old := reflect.ValueOf(c).Elem()
+	v := reflect.ValueOf(&cfg).Elem()
+	typ := v.Type()
+	var restart []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || liveKeys[key] {
+			continue
+		}
+		if key == "session_key_pair" && next.keysGenerated {
+			// the keys generated by Load differ every time.
+			v.Field(i).Set(old.Field(i))
+			cfg.keysGenerated = c.keysGenerated
+			continue
+		}
+		if !reflect.DeepEqual(old.Field(i).Interface(), v.Field(i).Interface()) {
+			restart = append(restart, key)
+			v.Field(i).Set(old.Field(i))
+		}
+	}
+	return &cfg, restart
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReloaded(t *testing.T) {
+	old := &Config{AppName: "old", Port: 8090, Database: "sqlite3", SessionKeyPair: []string{"a", "b"}}
+	next := &Config{AppName: "new", Port: 9000, Database: "sqlite3", SessionKeyPair: []string{"c", "d"}, keysGenerated: true, ReloadInterval: 5}
+	cfg, restart := old.Reloaded(next)
+	if cfg.AppName != "new" || cfg.Port != 8090 || !reflect.DeepEqual(cfg.SessionKeyPair, old.SessionKeyPair) {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	// the interval is only read by Serve when the App starts.
+	if cfg.ReloadInterval != 0 {
+		t.Errorf("expected the reload interval to be kept got %d", cfg.ReloadInterval)
+	}
+	if !reflect.DeepEqual(restart, []string{"port", "reload_interval"}) {
+		t.Errorf("expected port and reload_interval to require a restart got %v", restart)
+	}
+	if next.Port != 9000 {
+		t.Error("expected next to be unchanged")
+	}
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Secrets are the fields tagged secret, e.g. SessionKeyPair, and DatabaseConn
+// which is tagged secret:"conn" as a connection string. Their values can be
+// references instead of plain text:
+//
+//   - file:/run/secrets/db is replaced by the content of the file, without
+//     the trailing newline.
+//   - enc:... is decrypted with the master key, see Encrypt.
+//
+// SQLite connection strings are file: URIs, they are not references. Secrets
+// are redacted when the Config is printed, see Redacted.
+const (
+	// MasterKeyEnv is the environment variable holding the master key
+	// decrypting the enc: values, encoded in base64.
+	MasterKeyEnv = "UTRON_MASTER_KEY"
+
+	// MasterKeyFileEnv is the environment variable holding the path of a file
+	// with the master key, when MasterKeyEnv is not set.
+	MasterKeyFileEnv = "UTRON_MASTER_KEY_FILE"
+
+	filePrefix      = "file:"
+	encryptedPrefix = "enc:"
+	redacted        = "[redacted]"
+)
+
+// ErrNoMasterKey is returned when a value is encrypted and the master key is
+// not set.
+var ErrNoMasterKey = errors.New("utron: the master key is not set, set " + MasterKeyEnv + " or " + MasterKeyFileEnv)
+
+// MasterKey returns the master key, from MasterKeyEnv or the file named by
+// MasterKeyFileEnv. It returns ErrNoMasterKey when neither is set.
+func MasterKey() ([]byte, error) {
+	v := os.Getenv(MasterKeyEnv)
+	if v == "" {
+		file := os.Getenv(MasterKeyFileEnv)
+		if file == "" {
+			return nil, ErrNoMasterKey
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		v = string(data)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("utron: the master key must be 32 bytes encoded in base64")
+	}
+	return key, nil
+}
+
+// GenerateMasterKey returns a new random master key, encoded in base64.
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt encrypts value with the master key, using AES-256-GCM. The result
+// starts with enc: and can be used as the value of a secret setting.
+func Encrypt(key []byte, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt.
+func Decrypt(key []byte, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errors.New("utron: malformed encrypted value")
+	}
+	n := aead.NonceSize()
+	plain, err := aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return "", errors.New("utron: decrypting value, wrong master key or corrupted value")
+	}
+	return string(plain), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// resolveSecrets replaces the references in the secret fields of the struct v
+// by their values.
+func resolveSecrets(v reflect.Value) error {
+	var key []byte
+	return walkSecrets(v, "", true, func(name, value string) (string, error) {
+		switch {
+		case strings.HasPrefix(value, filePrefix):
+			data, err := ioutil.ReadFile(strings.TrimPrefix(value, filePrefix))
+			if err != nil {
+				return "", fmt.Errorf("utron: reading the secret %s %v", name, err)
+			}
+			return strings.TrimRight(string(data), "\r\n"), nil
+		case strings.HasPrefix(value, encryptedPrefix):
+			if key == nil {
+				k, err := MasterKey()
+				if err != nil {
+					return "", fmt.Errorf("utron: decrypting the secret %s %v", name, err)
+				}
+				key = k
+			}
+			plain, err := Decrypt(key, value)
+			if err != nil {
+				return "", fmt.Errorf("%v of %s", err, name)
+			}
+			return plain, nil
+		}
+		return value, nil
+	})
+}
+
+// walkSecrets calls fn with the values of the secret fields of the struct v,
+// and of its nested structs and maps of structs, and sets them to the values
+// returned. Slices and maps are replaced, not modified, so v can be a shallow
+// copy. Connection strings of SQLite databases are skipped when skipSQLite is
+// true.
+func walkSecrets(v reflect.Value, prefix string, skipSQLite bool, fn func(name, value string) (string, error)) error {
+	typ := v.Type()
+	sqlite := false
+	if db := v.FieldByName("Database"); skipSQLite && db.IsValid() && db.Kind() == reflect.String {
+		sqlite = db.String() == "sqlite3"
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := prefix + strings.Split(field.Tag.Get("json"), ",")[0]
+		f := v.Field(i)
+		if tag := field.Tag.Get("secret"); tag != "" {
+			if sqlite && tag == "conn" {
+				continue
+			}
+			switch f.Kind() {
+			case reflect.String:
+				s, err := fn(name, f.String())
+				if err != nil {
+					return err
+				}
+				f.SetString(s)
+			case reflect.Slice:
+				if f.Type().Elem().Kind() != reflect.String || f.IsNil() {
+					continue
+				}
+				l := reflect.MakeSlice(f.Type(), f.Len(), f.Len())
+				for j := 0; j < f.Len(); j++ {
+					s, err := fn(name, f.Index(j).String())
+					if err != nil {
+						return err
+					}
+					l.Index(j).SetString(s)
+				}
+				f.Set(l)
+			}
+			continue
+		}
+		switch f.Kind() {
+		case reflect.Struct:
+			if err := walkSecrets(f, name+".", skipSQLite, fn); err != nil {
+				return err
+			}
+		case reflect.Map:
+			if f.IsNil() || f.Type().Key().Kind() != reflect.String || f.Type().Elem().Kind() != reflect.Struct {
+				continue
+			}
+			m := reflect.MakeMapWithSize(f.Type(), f.Len())
+			for _, k := range f.MapKeys() {
+				e := reflect.New(f.Type().Elem()).Elem()
+				e.Set(f.MapIndex(k))
+				if err := walkSecrets(e, name+"."+k.String()+".", skipSQLite, fn); err != nil {
+					return err
+				}
+				m.SetMapIndex(k, e)
+			}
+			f.Set(m)
+		}
+	}
+	return nil
+}
+
+// KeysGenerated returns true when the SessionKeyPair was not set and random
+// keys were generated. Sessions are then lost when the App restarts.
+func (c *Config) KeysGenerated() bool {
+	return c.keysGenerated
+}
+
+// Redacted returns a copy of c with the values of the secret fields replaced
+// by [redacted]. Application sections are not included.
+func (c *Config) Redacted() *Config {
+	cfg := *c
+	cfg.sections = nil
+	_ = walkSecrets(reflect.ValueOf(&cfg).Elem(), "", false, func(_, value string) (string, error) {
+		if value == "" {
+			return value, nil
+		}
+		return redacted, nil
+	})
+	return &cfg
+}
+
+// String returns the settings of c in json, with the secrets redacted. It is
+// used when the Config is logged or printed with %v.
+func (c *Config) String() string {
+	data, err := json.Marshal(c.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// GoString is String, for the %#v format.
+func (c *Config) GoString() string {
+	return c.String()
+}
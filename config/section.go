@@ -0,0 +1,71 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Decode decodes the section of the configuration files into dst, which must
+// be a pointer. It allows application specific settings to live in the same
+// files as the utron settings, whatever their format, e.g. in app.toml
+//
+//	[payments]
+//	api_key = "secret"
+//	max_amount = 500
+//
+// is decoded with
+//
+//	var payments struct {
+//		APIKey    string `json:"api_key"`
+//		MaxAmount int    `json:"max_amount"`
+//	}
+//	err := cfg.Decode("payments", &payments)
+//
+// Keys are matched against the json tags, or the field names. Nested sections
+// are named with dots, e.g. "payments.stripe". Fields of dst which are not
+// in the section are left unchanged, so dst can hold defaults.
+//
+// The fields of a struct are then overridden by the environment like SyncEnv
+// does, the variable names being prefixed by the section name, e.g.
+// PAYMENTS_API_KEY, after EnvPrefix. Fields tagged secret:"true" can be
+// file: or enc: references, like the secret settings of Config.
+func (c *Config) Decode(section string, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("utron: decoding config section %s needs a non nil pointer got %T", section, dst)
+	}
+	if s, ok := c.Section(section); ok {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(data, dst); err != nil {
+			return fmt.Errorf("utron: decoding config section %s %v", section, err)
+		}
+	}
+	if e := v.Elem(); e.Kind() == reflect.Struct {
+		if err := syncEnv(e, c.EnvPrefix+envKey(section)+"_"); err != nil {
+			return err
+		}
+		return resolveSecrets(e)
+	}
+	return nil
+}
+
+// Section returns the raw settings of section, as decoded from the
+// configuration files, and whether it exists.
+func (c *Config) Section(section string) (interface{}, bool) {
+	var v interface{} = c.sections
+	for _, key := range strings.Split(section, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, v != nil
+}
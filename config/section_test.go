@@ -0,0 +1,74 @@
+package config
+
+import "testing"
+
+type payments struct {
+	APIKey    string `json:"api_key"`
+	MaxAmount int    `json:"max_amount"`
+	Enabled   bool   `json:"enabled"`
+	Retries   int    `json:"retries"`
+}
+
+func TestDecode(t *testing.T) {
+	t.Setenv("APP_NAME", "")
+	for _, ext := range []string{".json", ".toml", ".yml", ".hcl"} {
+		cfg, err := NewConfig("../fixtures/sections/app" + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.AppName != "shop" {
+			t.Errorf("%s: expected shop got %s", ext, cfg.AppName)
+		}
+		p := payments{Retries: 3}
+		if err = cfg.Decode("payments", &p); err != nil {
+			t.Fatal(err)
+		}
+		expect := payments{APIKey: "secret", MaxAmount: 500, Enabled: true, Retries: 3}
+		if p != expect {
+			t.Errorf("%s: expected %+v got %+v", ext, expect, p)
+		}
+		var stripe struct{ Currency string }
+		if err = cfg.Decode("payments.stripe", &stripe); err != nil {
+			t.Fatal(err)
+		}
+		if stripe.Currency != "eur" {
+			t.Errorf("%s: expected eur got %s", ext, stripe.Currency)
+		}
+	}
+
+	cfg, err := NewConfig("../fixtures/sections/app.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PAYMENTS_API_KEY", "from env")
+	t.Setenv("PAYMENTS_STRIPE_CURRENCY", "usd")
+	var p payments
+	if err = cfg.Decode("payments", &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.APIKey != "from env" {
+		t.Errorf("expected the environment to override the file got %s", p.APIKey)
+	}
+	var stripe struct{ Currency string }
+	if err = cfg.Decode("payments.stripe", &stripe); err != nil {
+		t.Fatal(err)
+	}
+	if stripe.Currency != "usd" {
+		t.Errorf("expected usd got %s", stripe.Currency)
+	}
+
+	// missing sections leave the defaults.
+	p = payments{Retries: 3}
+	if err = cfg.Decode("nothing", &p); err != nil || p.Retries != 3 {
+		t.Errorf("expected the defaults got %+v %v", p, err)
+	}
+	if _, ok := cfg.Section("payments.stripe.currency"); !ok {
+		t.Error("expected the nested key to be found")
+	}
+	if err = cfg.Decode("payments", p); err == nil {
+		t.Error("expected an error for a non pointer")
+	}
+	if err = cfg.Decode("payments.max_amount", &p); err == nil {
+		t.Error("expected an error for a mismatched type")
+	}
+}
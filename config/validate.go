@@ -0,0 +1,307 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Dialects are the database dialects known to gorm. The driver of a dialect is
+// registered by importing its package, e.g. github.com/jinzhu/gorm/dialects/postgres.
+var Dialects = []string{"mysql", "postgres", "cloudsqlpostgres", "sqlite3", "mssql"}
+
+var (
+	sessionStores  = []string{"ql", "cookie", "file"}
+	sameSiteValues = []string{"lax", "strict", "none"}
+	tlsVersions    = []string{"1.0", "1.1", "1.2", "1.3"}
+	clientAuths    = []string{"none", "request", "require", "verify_if_given", "require_and_verify"}
+	logLevels      = []string{"debug", "info", "warn", "warning", "error"}
+	logFormats     = []string{"text", "json", "logfmt"}
+)
+
+// Problem is an invalid setting found by Validate.
+type Problem struct {
+	// File is the configuration file setting Key, it is empty when the
+	// setting comes from a default or the environment.
+	File    string
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.File == "" {
+		return p.Key + ": " + p.Message
+	}
+	return p.File + ": " + p.Key + ": " + p.Message
+}
+
+// ValidationError is returned by Validate, it lists every problem of the
+// configuration.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, fmt.Sprintf("utron: invalid configuration, %d problems", len(e.Problems)))
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Files returns the configuration files c was loaded from, in order.
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// Source returns the configuration file setting key, or its closest parent.
+// It is empty when the setting comes from a default or the environment.
+func (c *Config) Source(key string) string {
+	for {
+		if file, ok := c.sources[key]; ok {
+			return file
+		}
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			return ""
+		}
+		key = key[:i]
+	}
+}
+
+// Validate checks the settings of c, and returns a *ValidationError listing
+// all the problems found, with the file and key of each setting:
+//
+//	* unknown keys, e.g. typos. Unknown sections are application settings,
+//	  see Decode.
+//	* missing required settings, e.g. view_dir or database unless no_model
+//	  is set.
+//	* ports and durations out of range.
+//	* unknown values, e.g. database dialects, session stores or TLS
+//	  versions.
+//	* missing directories and files, e.g. view_dir, unless embedded is set,
+//	  and the TLS certificates.
+//
+// static_dir, migrations_dir and locales_dir are optional, they may not
+// exist. port is optional too, the server listens on DefaultPort when it is
+// 0.
+func (c *Config) Validate() error {
+	v := &validator{cfg: c}
+	v.unknownKeys()
+
+	v.oneOf("log_level", strings.ToLower(c.LogLevel), logLevels)
+	v.oneOf("log_format", c.LogFormat, logFormats)
+
+	if c.Port != 0 {
+		v.port("port", c.Port)
+	}
+	if c.HTTPRedirectPort != 0 {
+		v.port("http_redirect_port", c.HTTPRedirectPort)
+		if port := c.ServerPort(); c.HTTPRedirectPort == port {
+			v.add("http_redirect_port", "must be different from port %d", port)
+		}
+	}
+	v.positive("read_timeout", c.ReadTimeout)
+	v.positive("read_header_timeout", c.ReadHeaderTimeout)
+	v.positive("write_timeout", c.WriteTimeout)
+	v.positive("idle_timeout", c.IdleTimeout)
+	v.positive("shutdown_timeout", c.ShutdownTimeout)
+	v.positive("hsts_max_age", c.HSTSMaxAge)
+
+	if c.TLSCert != "" || c.TLSKey != "" {
+		v.required("tls_cert", c.TLSCert)
+		v.required("tls_key", c.TLSKey)
+		v.file("tls_cert", c.TLSCert)
+		v.file("tls_key", c.TLSKey)
+		v.oneOf("tls_min_version", c.TLSMinVersion, tlsVersions)
+		v.file("tls_client_ca", c.TLSClientCA)
+		v.oneOf("tls_client_auth", c.TLSClientAuth, clientAuths)
+		switch c.TLSClientAuth {
+		case "verify_if_given", "require_and_verify":
+			if c.TLSClientCA == "" {
+				v.add("tls_client_auth", "%s needs tls_client_ca", c.TLSClientAuth)
+			}
+		}
+	}
+
+	if v.required("view_dir", c.ViewsDir) && !c.Embedded {
+		v.dir("view_dir", c.ViewsDir)
+	}
+
+	if !c.NoModel {
+		v.database("", c.DatabaseConfig())
+	}
+	v.positive("database_max_open_conns", c.DatabaseMaxOpenConns)
+	v.positive("database_max_idle_conns", c.DatabaseMaxIdleConns)
+	v.positive("database_conn_max_lifetime", c.DatabaseConnMaxLifetime)
+	v.positive("database_conn_max_idle_time", c.DatabaseConnMaxIdleTime)
+	names := make([]string, 0, len(c.Databases))
+	for name := range c.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.database("databases."+name+".", c.Databases[name])
+	}
+
+	v.oneOf("session_store", c.SessionStore, sessionStores)
+	v.oneOf("session_same_site", strings.ToLower(c.SessionSameSite), sameSiteValues)
+	v.positive("session_max_age", c.SessionMaxAge)
+	if c.SessionStore == "file" && c.SessionDir != "" {
+		v.dir("session_dir", c.SessionDir)
+	}
+	for i, key := range c.SessionKeyPair {
+		if i%2 == 0 && key == "" {
+			v.add("session_key_pair", "authentication key %d is empty", i)
+		}
+		if i%2 == 1 {
+			switch len(key) {
+			case 0, 16, 24, 32:
+			default:
+				v.add("session_key_pair", "encryption key %d must be 16, 24 or 32 bytes long", i)
+			}
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validator collects the problems of cfg.
+type validator struct {
+	cfg      *Config
+	problems []Problem
+}
+
+// add records a problem with the setting key.
+func (v *validator) add(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		File:    v.cfg.Source(key),
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) required(key, value string) bool {
+	if value == "" {
+		v.add(key, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) port(key string, n int) {
+	if n < 1 || n > 65535 {
+		v.add(key, "must be between 1 and 65535, got %d", n)
+	}
+}
+
+func (v *validator) positive(key string, n int) {
+	if n < 0 {
+		v.add(key, "must not be negative, got %d", n)
+	}
+}
+
+func (v *validator) oneOf(key, value string, values []string) {
+	if value == "" {
+		return
+	}
+	for _, s := range values {
+		if s == value {
+			return
+		}
+	}
+	v.add(key, "unknown value %q, supported are %s", value, strings.Join(values, ", "))
+}
+
+func (v *validator) dir(key, path string) {
+	info, err := os.Stat(path)
+	switch {
+	case err != nil:
+		v.add(key, "directory %s does not exist", path)
+	case !info.IsDir():
+		v.add(key, "%s is not a directory", path)
+	}
+}
+
+func (v *validator) file(key, path string) {
+	if path == "" {
+		return
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err != nil:
+		v.add(key, "file %s does not exist", path)
+	case info.IsDir():
+		v.add(key, "%s is a directory", path)
+	}
+}
+
+// database checks the settings of a database, prefix is the prefix of their
+// keys.
+func (v *validator) database(prefix string, dc DatabaseConfig) {
+	if v.required(prefix+"database", dc.Database) {
+		v.oneOf(prefix+"database", dc.Database, Dialects)
+	}
+	v.required(prefix+"database_conn", dc.DatabaseConn)
+	if prefix != "" {
+		v.positive(prefix+"max_open_conns", dc.MaxOpenConns)
+		v.positive(prefix+"max_idle_conns", dc.MaxIdleConns)
+		v.positive(prefix+"conn_max_lifetime", dc.ConnMaxLifetime)
+		v.positive(prefix+"conn_max_idle_time", dc.ConnMaxIdleTime)
+	}
+}
+
+// unknownKeys reports the keys of the configuration files which are not
+// settings. Unknown sections are allowed.
+func (v *validator) unknownKeys() {
+	known := jsonKeys(reflect.TypeOf(Config{}))
+	dbKnown := jsonKeys(reflect.TypeOf(DatabaseConfig{}))
+	for _, key := range sortedKeys(v.cfg.sections) {
+		value := v.cfg.sections[key]
+		if !known[key] {
+			if _, section := value.(map[string]interface{}); !section {
+				v.add(key, "unknown setting")
+			}
+			continue
+		}
+		if key != "databases" {
+			continue
+		}
+		dbs, _ := value.(map[string]interface{})
+		for _, name := range sortedKeys(dbs) {
+			db, _ := dbs[name].(map[string]interface{})
+			for _, k := range sortedKeys(db) {
+				if !dbKnown[k] {
+					v.add("databases."+name+"."+k, "unknown setting")
+				}
+			}
+		}
+	}
+}
+
+// jsonKeys returns the json names of the fields of the struct typ.
+func jsonKeys(typ reflect.Type) map[string]bool {
+	keys := make(map[string]bool, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
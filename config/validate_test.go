@@ -0,0 +1,81 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, k := range []string{"PORT", "DATABASE", "DATABASE_CONN", "VIEWS_DIR", "APP_NAME"} {
+		t.Setenv(k, "")
+	}
+	cfg, err := LoadProfile("../fixtures/invalid", "app", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error got %v", err)
+	}
+	hcl := "../fixtures/invalid/app.hcl"
+	toml := "../fixtures/invalid/app.production.toml"
+	expect := []Problem{
+		{hcl, "databases.analytics.databse_conn", "unknown setting"},
+		{hcl, "prot", "unknown setting"},
+		{toml, "port", "must be between 1 and 65535, got 70000"},
+		{"", "tls_key", "is required"},
+		{toml, "tls_cert", "file missing.crt does not exist"},
+		{hcl, "view_dir", "directory fixtures/missing does not exist"},
+		{hcl, "database", `unknown value "postgress", supported are mysql, postgres, cloudsqlpostgres, sqlite3, mssql`},
+		{"", "database_conn", "is required"},
+		{hcl, "databases.analytics.database_conn", "is required"},
+		{hcl, "session_store", `unknown value "redis", supported are ql, cookie, file`},
+	}
+	if len(verr.Problems) != len(expect) {
+		t.Fatalf("expected %d problems got %v", len(expect), verr)
+	}
+	for i, p := range expect {
+		if verr.Problems[i] != p {
+			t.Errorf("expected %v got %v", p, verr.Problems[i])
+		}
+	}
+
+	cfg = &Config{
+		Port:           8090,
+		ViewsDir:       "../fixtures/view",
+		NoModel:        true,
+		SessionKeyPair: []string{"auth", "0123456789abcdef"},
+	}
+	if err = cfg.Validate(); err != nil {
+		t.Errorf("expected a valid config got %v", err)
+	}
+	cfg.SessionKeyPair = []string{"", "short"}
+	cfg.SessionSameSite = "Lax"
+	cfg.ReadTimeout = -1
+	cfg.LogFormat = "xml"
+	cfg.NoModel = false
+	cfg.Database = "pgx"
+	cfg.DatabaseConn = "postgres://localhost/app"
+	err = cfg.Validate()
+	if verr, ok = err.(*ValidationError); !ok || len(verr.Problems) != 5 {
+		t.Errorf("expected 5 problems got %v", err)
+	}
+
+	// a port is optional, the default is used.
+	cfg = &Config{
+		ViewsDir:     "../fixtures/view",
+		Database:     "postgres",
+		DatabaseConn: "postgres://localhost/app",
+	}
+	if err = cfg.Validate(); err != nil {
+		t.Errorf("expected a valid config got %v", err)
+	}
+	if p := cfg.ServerPort(); p != DefaultPort {
+		t.Errorf("expected %d got %d", DefaultPort, p)
+	}
+	cfg.HTTPRedirectPort = DefaultPort
+	err = cfg.Validate()
+	if verr, ok = err.(*ValidationError); !ok || len(verr.Problems) != 1 || verr.Problems[0].Key != "http_redirect_port" {
+		t.Errorf("expected the redirect port to clash with the default port got %v", err)
+	}
+}
@@ -15,6 +15,9 @@ type Controller interface {
 
 // BaseController implements the Controller interface, It is recommended all
 // user defined Controllers should embed *BaseController.
+//
+// Controllers can declare a Layout string field, its value is the default
+// layout for the templates rendered by the controller.
 type BaseController struct {
 	Ctx    *base.Context
 	Routes []string
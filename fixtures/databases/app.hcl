@@ -0,0 +1,11 @@
+database = "postgres"
+database_conn = "postgres://localhost/app"
+database_replicas = ["postgres://replica1/app", "postgres://replica2/app"]
+database_max_open_conns = 20
+database_conn_max_lifetime = 300
+
+databases "analytics" {
+  database = "mysql"
+  database_conn = "root@/analytics"
+  max_open_conns = 5
+}
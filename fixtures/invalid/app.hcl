@@ -0,0 +1,14 @@
+app_name = "utron web app"
+prot = 8090
+view_dir = "fixtures/missing"
+database = "postgress"
+session_store = "redis"
+
+databases "analytics" {
+  database = "sqlite3"
+  databse_conn = "analytics.db"
+}
+
+payments {
+  api_key = "secret"
+}
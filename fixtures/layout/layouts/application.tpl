@@ -0,0 +1,3 @@
+<title>{{block "title" .}}utron{{end}}</title>
+{{template "partials/header" .}}
+{{- yield}}
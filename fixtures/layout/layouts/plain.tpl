@@ -0,0 +1 @@
+plain:{{yield}}
\ No newline at end of file
@@ -0,0 +1,4 @@
+{{/* layout: application */ -}}
+{{define "title"}}one{{end}}
+{{- define "content"}}first {{.Name}}{{end}}
+{{- template "content" .}}
\ No newline at end of file
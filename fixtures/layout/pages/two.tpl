@@ -0,0 +1,2 @@
+{{define "content"}}second {{.Name}}{{end}}
+{{- template "content" .}}
\ No newline at end of file
@@ -0,0 +1,11 @@
+app_name = "shop"
+
+payments {
+  api_key = "secret"
+  max_amount = 500
+  enabled = true
+
+  stripe {
+    currency = "eur"
+  }
+}
@@ -1,36 +1,28 @@
+// Package flash provides the original flash messages API. New code should use
+// the Flasher returned by Context.Flash, which saves messages automatically and
+// passes them to templates.
 package flash
 
 import (
-	"encoding/gob"
-	"errors"
-
 	"github.com/gernest/utron/base"
 )
 
 const (
 	// FlashSuccess is the context key for success flash messages
-	FlashSuccess = "FlashSuccess"
+	FlashSuccess = base.FlashSuccess
 
 	// FlashWarn is a context key for warning flash messages
-	FlashWarn = "FlashWarn"
+	FlashWarn = base.FlashWarn
 
 	// FlashErr is a context key for flash error message
-	FlashErr = "FlashError"
+	FlashErr = base.FlashErr
 )
 
-func init() {
-	gob.Register(&Flash{})
-	gob.Register(Flashes{})
-}
-
 // Flash implements flash messages, like ones in gorilla/sessions
-type Flash struct {
-	Kind    string
-	Message string
-}
+type Flash = base.Flash
 
 // Flashes is a collection of flash messages
-type Flashes []*Flash
+type Flashes = base.Flashes
 
 // GetFlashes retieves all flash messages found in a cookie session associated with ctx..
 //
@@ -39,7 +31,13 @@ type Flashes []*Flash
 // session for flash messages from other sessions.
 //
 // key is the key that is used to identiry which flash messages are of interest.
+//
+// When name and key are empty, the flash messages saved with Context.Flash are
+// returned. No error is returned when there are no flash messages.
 func GetFlashes(ctx *base.Context, name, key string) (Flashes, error) {
+	if name == "" && key == "" {
+		return ctx.Flashes()
+	}
 	ss, err := ctx.GetSession(name)
 	if err != nil {
 		return nil, err
@@ -50,9 +48,10 @@ func GetFlashes(ctx *base.Context, name, key string) (Flashes, error) {
 		if serr != nil {
 			return nil, serr
 		}
-		return v.(Flashes), nil
+		f, _ := base.ToFlashes(v)
+		return f, nil
 	}
-	return nil, errors.New("no flashes found")
+	return nil, nil
 }
 
 // AddFlashToCtx takes flash messages stored in a cookie which is associated with the
@@ -65,7 +64,9 @@ func AddFlashToCtx(ctx *base.Context, name, key string) error {
 	if err != nil {
 		return err
 	}
-	ctx.SetData(key, f)
+	if f != nil {
+		ctx.SetData(key, f)
+	}
 	return nil
 }
 
@@ -82,7 +83,7 @@ func New() *Flasher {
 
 // Add adds the flash message
 func (f *Flasher) Add(kind, message string) {
-	fl := &Flash{kind, message}
+	fl := &Flash{Kind: kind, Message: message}
 	f.f = append(f.f, fl)
 }
 
@@ -101,15 +102,25 @@ func (f *Flasher) Warn(msg string) {
 	f.Add(FlashWarn, msg)
 }
 
-// Save saves flash messages to context
+// Save saves flash messages to context.
+//
+// When name and key are empty, the messages are handed to Context.Flash and
+// saved when the context is committed.
 func (f *Flasher) Save(ctx *base.Context, name, key string) error {
+	if name == "" && key == "" {
+		for _, v := range f.f {
+			ctx.Flash().AddData(v.Kind, v.Message, v.Data)
+		}
+		f.f = nil
+		return nil
+	}
 	ss, err := ctx.GetSession(name)
 	if err != nil {
 		return err
 	}
 	var flashes Flashes
-	if v, ok := ss.Values[key]; ok {
-		flashes = v.(Flashes)
+	if v, ok := base.ToFlashes(ss.Values[key]); ok {
+		flashes = v
 	}
 	ss.Values[key] = append(flashes, f.f...)
 	err = ss.Save(ctx.Request(), ctx.Response())
@@ -8,6 +8,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/gernest/utron/base"
 	"github.com/gernest/utron/controller"
 	"github.com/gernest/utron/logger"
 	"github.com/gernest/utron/router"
@@ -81,3 +82,21 @@ func TestFlash(t *testing.T) {
 		t.Errorf("expected 3 got %d", len(result))
 	}
 }
+
+func TestNoFlashes(t *testing.T) {
+	o := &router.Options{
+		SessionStore: sessions.NewCookieStore([]byte("ePAPW9vJv7gHoftvQTyNj5VkWB52mlza")),
+	}
+	r := router.NewRouter(o)
+	var ferr error
+	r.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		ctx := base.NewContext(w, req)
+		ctx.SessionStore = o.SessionStore
+		_, ferr = GetFlashes(ctx, fname, fkey)
+	})
+	req, _ := http.NewRequest("GET", "/", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if ferr != nil {
+		t.Errorf("expected no error got %v", ferr)
+	}
+}
@@ -0,0 +1,112 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type contextKey int
+
+// localeKey is the request context key of the locale found in the URL prefix.
+const localeKey contextKey = iota
+
+// Middleware removes the locale prefix from the URL path, so /fr/products is
+// routed as /products with the locale fr. Only locales with a catalog are
+// removed.
+func (b *Bundle) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l, rest, ok := b.FromPath(r.URL.Path); ok {
+			r = r.WithContext(context.WithValue(r.Context(), localeKey, l))
+			u := *r.URL
+			u.Path = rest
+			u.RawPath = ""
+			r.URL = &u
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// FromPath returns the locale which is the first segment of the URL path p, and
+// the path without it.
+func (b *Bundle) FromPath(p string) (locale, rest string, ok bool) {
+	seg := strings.TrimPrefix(p, "/")
+	if i := strings.Index(seg, "/"); i >= 0 {
+		seg = seg[:i]
+	}
+	for l := range b.catalogs {
+		if strings.EqualFold(l, seg) {
+			rest = strings.TrimPrefix(p, "/"+seg)
+			if rest == "" {
+				rest = "/"
+			}
+			return l, rest, true
+		}
+	}
+	return "", p, false
+}
+
+// Detect returns the locale of the request r. The locale is taken from the
+// first of these with a catalog
+//
+//	the URL prefix, when the request went through Middleware
+//	the cookie named cookie
+//	the locale stored in the session, passed as session
+//	the Accept-Language header
+//
+// and defaults to the DefaultLocale.
+func (b *Bundle) Detect(r *http.Request, cookie, session string) string {
+	var candidates []string
+	if l, ok := r.Context().Value(localeKey).(string); ok {
+		candidates = append(candidates, l)
+	}
+	if cookie != "" {
+		if c, err := r.Cookie(cookie); err == nil {
+			candidates = append(candidates, c.Value)
+		}
+	}
+	if session != "" {
+		candidates = append(candidates, session)
+	}
+	candidates = append(candidates, ParseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+	return b.Match(candidates...)
+}
+
+// ParseAcceptLanguage returns the locales listed in the Accept-Language header
+// value h, ordered by preference.
+func ParseAcceptLanguage(h string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(h, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			langs = append(langs, lang{tag, q})
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
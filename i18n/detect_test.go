@@ -0,0 +1,79 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	sample := []struct {
+		header string
+		expect []string
+	}{
+		{"", []string{}},
+		{"fr", []string{"fr"}},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", []string{"fr-CH", "fr", "en", "de"}},
+		{"en;q=0.5, sw", []string{"sw", "en"}},
+		{"en;q=0, sw", []string{"sw"}},
+	}
+	for _, s := range sample {
+		v := ParseAcceptLanguage(s.header)
+		if !reflect.DeepEqual(v, s.expect) {
+			t.Errorf("%q: expected %v got %v", s.header, s.expect, v)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	b := NewBundle("en")
+	for _, l := range []string{"en", "fr", "de"} {
+		_ = b.Add(l, map[string]interface{}{"k": l})
+	}
+
+	sample := []struct {
+		path, cookie, session, accept, expect string
+	}{
+		{"/", "", "", "", "en"},
+		{"/", "", "", "de-AT, fr;q=0.5", "de"},
+		{"/", "", "fr", "de", "fr"},
+		{"/", "de", "fr", "en", "de"},
+		// without Middleware the path is not a locale prefix.
+		{"/fr/products", "de", "", "", "de"},
+		{"/fr", "", "", "", "en"},
+		{"/products", "sw", "", "jp", "en"},
+	}
+	for _, s := range sample {
+		req := httptest.NewRequest("GET", s.path, nil)
+		if s.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "locale", Value: s.cookie})
+		}
+		if s.accept != "" {
+			req.Header.Set("Accept-Language", s.accept)
+		}
+		if l := b.Detect(req, "locale", s.session); l != s.expect {
+			t.Errorf("%v: expected %s got %s", s, s.expect, l)
+		}
+	}
+
+	var path, locale string
+	h := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		locale = b.Detect(r, "", "")
+	}))
+	paths := []struct {
+		path, expect, locale string
+	}{
+		{"/fr/products/1", "/products/1", "fr"},
+		{"/de", "/", "de"},
+		{"/french/toast", "/french/toast", "en"},
+		{"/products", "/products", "en"},
+	}
+	for _, p := range paths {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", p.path, nil))
+		if path != p.expect || locale != p.locale {
+			t.Errorf("%s: expected %s %s got %s %s", p.path, p.expect, p.locale, path, locale)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+greeting = "Hallo %s"
+
+home {
+  title = "Willkommen"
+}
+
+cart {
+  items {
+    one = "%d Artikel"
+    other = "%d Artikel"
+  }
+}
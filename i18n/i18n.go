@@ -0,0 +1,306 @@
+// Package i18n translates messages. Message catalogs are loaded from a locales
+// directory holding one file per locale, named after the locale, e.g.
+//
+//	locales/en.json
+//	locales/fr.toml
+//	locales/pt-BR.yml
+//	locales/de.hcl
+//
+// Any of the config file formats can be used. Nested keys are joined with dots,
+// so this catalog
+//
+//	[home]
+//	title = "Welcome"
+//
+//	[cart.items]
+//	one = "%d item"
+//	other = "%d items"
+//
+// defines the messages home.title and cart.items. A message with the plural
+// categories zero, one, two, few, many and other as keys is plural, the form is
+// picked by the count passed as first argument using the plural rule of the
+// locale.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"gopkg.in/yaml.v2"
+)
+
+// pluralForms are the plural categories, the other form is required in plural
+// messages.
+var pluralForms = map[string]bool{
+	"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true,
+}
+
+// message is a translated message. Singular messages only have the other
+// form.
+type message map[string]string
+
+// Bundle holds the message catalogs of all locales.
+type Bundle struct {
+	// DefaultLocale is used when no locale is detected, and for messages
+	// missing in a locale.
+	DefaultLocale string
+
+	catalogs map[string]map[string]message
+	rules    map[string]PluralRule
+}
+
+// NewBundle returns an empty Bundle falling back to defaultLocale.
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		DefaultLocale: defaultLocale,
+		catalogs:      make(map[string]map[string]message),
+		rules:         make(map[string]PluralRule),
+	}
+}
+
+// LoadDir loads the catalogs found in dir.
+func (b *Bundle) LoadDir(dir string) error {
+	return b.Load(os.DirFS(dir))
+}
+
+// Load loads the catalogs at the root of fsys. Files with extensions other than
+// .json, .toml, .yml and .hcl are ignored.
+func (b *Bundle) Load(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch path.Ext(e.Name()) {
+		case ".json", ".toml", ".yml", ".hcl":
+		default:
+			continue
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return err
+		}
+		if err = b.AddFile(e.Name(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddFile decodes data as the catalog file name. The locale is the file name
+// without extension, and the format is deduced from the extension.
+func (b *Bundle) AddFile(name string, data []byte) error {
+	ext := path.Ext(name)
+	locale := strings.TrimSuffix(path.Base(name), ext)
+	var v interface{}
+	switch ext {
+	case ".json":
+		m := make(map[string]interface{})
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("utron: locale %s: %v", name, err)
+		}
+		v = m
+	case ".toml":
+		m := make(map[string]interface{})
+		if _, err := toml.Decode(string(data), &m); err != nil {
+			return fmt.Errorf("utron: locale %s: %v", name, err)
+		}
+		v = m
+	case ".yml":
+		m := make(map[interface{}]interface{})
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("utron: locale %s: %v", name, err)
+		}
+		v = m
+	case ".hcl":
+		m := make(map[string]interface{})
+		if err := hcl.Decode(&m, string(data)); err != nil {
+			return fmt.Errorf("utron: locale %s: %v", name, err)
+		}
+		v = m
+	default:
+		return fmt.Errorf("utron: locale %s: format not supported", name)
+	}
+	return b.add(locale, "", v)
+}
+
+// Add adds the messages to the catalog of locale. Values are strings, or maps
+// of nested messages or plural forms.
+func (b *Bundle) Add(locale string, messages map[string]interface{}) error {
+	return b.add(locale, "", messages)
+}
+
+func (b *Bundle) add(locale, prefix string, v interface{}) error {
+	c, ok := b.catalogs[locale]
+	if !ok {
+		c = make(map[string]message)
+		b.catalogs[locale] = c
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if m, ok := pluralMessage(rv); ok {
+			c[prefix] = m
+			return nil
+		}
+		for _, k := range rv.MapKeys() {
+			key := fmt.Sprint(k.Interface())
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if err := b.add(locale, key, rv.MapIndex(k).Interface()); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		// hcl decodes objects into slices of maps.
+		for i := 0; i < rv.Len(); i++ {
+			if err := b.add(locale, prefix, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	default:
+		if prefix == "" {
+			return fmt.Errorf("utron: locale %s: expected a map of messages", locale)
+		}
+		c[prefix] = message{"other": fmt.Sprint(v)}
+	}
+	return nil
+}
+
+// pluralMessage returns the message if all keys of the map rv are plural
+// categories, and the other form is set.
+func pluralMessage(rv reflect.Value) (message, bool) {
+	m := make(message)
+	for _, k := range rv.MapKeys() {
+		key := fmt.Sprint(k.Interface())
+		if !pluralForms[key] {
+			return nil, false
+		}
+		v := rv.MapIndex(k).Interface()
+		switch v.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []map[string]interface{}:
+			return nil, false
+		}
+		m[key] = fmt.Sprint(v)
+	}
+	if _, ok := m["other"]; !ok {
+		return nil, false
+	}
+	return m, true
+}
+
+// Locales returns the locales with a catalog, sorted.
+func (b *Bundle) Locales() []string {
+	var locales []string
+	for k := range b.catalogs {
+		locales = append(locales, k)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Match returns the first of the candidates with a catalog. A candidate
+// matches its exact locale, or the locale of its language, e.g. en-GB matches
+// en. The DefaultLocale is returned when nothing matches.
+func (b *Bundle) Match(candidates ...string) string {
+	for _, c := range candidates {
+		if l, ok := b.match(c); ok {
+			return l
+		}
+	}
+	return b.DefaultLocale
+}
+
+func (b *Bundle) match(candidate string) (string, bool) {
+	candidate = strings.Replace(strings.TrimSpace(candidate), "_", "-", -1)
+	if candidate == "" {
+		return "", false
+	}
+	for l := range b.catalogs {
+		if strings.EqualFold(l, candidate) {
+			return l, true
+		}
+	}
+	lang := language(candidate)
+	for l := range b.catalogs {
+		if strings.EqualFold(l, lang) {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// T returns the message key translated to locale. The message is looked up in
+// the locale, its language and the DefaultLocale, in that order. When the key
+// is missing everywhere the key itself is returned.
+//
+// For plural messages the first argument is the count. The message is
+// formatted with args using fmt.Sprintf when it has formatting verbs.
+func (b *Bundle) T(locale, key string, args ...interface{}) string {
+	m, l, ok := b.lookup(locale, key)
+	if !ok {
+		return format(key, args)
+	}
+	text, ok := m["other"]
+	if len(m) > 1 && len(args) > 0 {
+		if n, isCount := count(args[0]); isCount {
+			if form, ok := m["zero"]; ok && n == 0 {
+				text = form
+			} else if form, ok := m[b.pluralRule(l)(n)]; ok {
+				text = form
+			}
+		}
+	}
+	return format(text, args)
+}
+
+func (b *Bundle) lookup(locale, key string) (message, string, bool) {
+	for _, l := range []string{locale, language(locale), b.DefaultLocale} {
+		if m, ok := b.catalogs[l][key]; ok {
+			return m, l, true
+		}
+	}
+	return nil, "", false
+}
+
+// format formats text with args, when text has formatting verbs.
+func format(text string, args []interface{}) string {
+	if len(args) == 0 || !strings.Contains(text, "%") {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// count returns v as an int, if it is a number.
+func count(v interface{}) (int, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return int(rv.Float()), true
+	}
+	return 0, false
+}
+
+// language returns the language part of locale, e.g. en for en-US.
+func language(locale string) string {
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		return locale[:i]
+	}
+	return locale
+}
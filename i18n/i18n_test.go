@@ -0,0 +1,106 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	b := NewBundle("en")
+	if err := b.LoadDir("fixtures/locales"); err != nil {
+		t.Fatal(err)
+	}
+	locales := []string{"de", "en", "fr", "ru"}
+	if l := b.Locales(); !reflect.DeepEqual(l, locales) {
+		t.Fatalf("expected %v got %v", locales, l)
+	}
+
+	sample := []struct {
+		locale, key string
+		args        []interface{}
+		expect      string
+	}{
+		{"en", "home.title", nil, "Welcome"},
+		{"fr", "home.title", nil, "Bienvenue"},
+		{"ru", "home.title", nil, "Добро пожаловать"},
+		{"de", "home.title", nil, "Willkommen"},
+		{"fr-CA", "home.title", nil, "Bienvenue"},
+		{"fr", "greeting", []interface{}{"gernest"}, "Bonjour gernest"},
+		{"de", "greeting", []interface{}{"gernest"}, "Hallo gernest"},
+		{"fr", "only_en", nil, "English only"},
+		{"sw", "home.title", nil, "Welcome"},
+		{"en", "missing.key", nil, "missing.key"},
+
+		{"en", "cart.items", []interface{}{0}, "Your cart is empty"},
+		{"en", "cart.items", []interface{}{1}, "1 item"},
+		{"en", "cart.items", []interface{}{2}, "2 items"},
+		{"fr", "cart.items", []interface{}{0}, "0 article"},
+		{"fr", "cart.items", []interface{}{1}, "1 article"},
+		{"fr", "cart.items", []interface{}{5}, "5 articles"},
+		{"ru", "cart.items", []interface{}{1}, "1 товар"},
+		{"ru", "cart.items", []interface{}{3}, "3 товара"},
+		{"ru", "cart.items", []interface{}{5}, "5 товаров"},
+		{"ru", "cart.items", []interface{}{11}, "11 товаров"},
+		{"ru", "cart.items", []interface{}{21}, "21 товар"},
+		{"de", "cart.items", []interface{}{int64(3)}, "3 Artikel"},
+	}
+	for _, s := range sample {
+		v := b.T(s.locale, s.key, s.args...)
+		if v != s.expect {
+			t.Errorf("%s %s %v: expected %q got %q", s.locale, s.key, s.args, s.expect, v)
+		}
+	}
+
+	if err := b.LoadDir("fixtures/missing"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if err := b.AddFile("en.json", []byte("{")); err == nil {
+		t.Error("expected an error for a bad catalog")
+	}
+}
+
+func TestPluralRules(t *testing.T) {
+	sample := []struct {
+		locale string
+		n      int
+		expect string
+	}{
+		{"en", 1, "one"},
+		{"en", 0, "other"},
+		{"en-US", 2, "other"},
+		{"fr", 0, "one"},
+		{"ja", 1, "other"},
+		{"pl", 1, "one"},
+		{"pl", 22, "few"},
+		{"pl", 12, "many"},
+		{"pl", 25, "many"},
+		{"ru", 101, "one"},
+		{"ru", 111, "many"},
+		{"cs", 3, "few"},
+		{"cs", 5, "other"},
+		{"ar", 0, "zero"},
+		{"ar", 2, "two"},
+		{"ar", 105, "few"},
+		{"ar", 111, "many"},
+		{"ar", 100, "other"},
+	}
+	b := NewBundle("en")
+	for _, s := range sample {
+		if v := b.pluralRule(s.locale)(s.n); v != s.expect {
+			t.Errorf("%s %d: expected %s got %s", s.locale, s.n, s.expect, v)
+		}
+	}
+
+	_ = b.Add("en", map[string]interface{}{
+		"apples": map[string]interface{}{"one": "an apple", "two": "a pair of apples", "other": "apples"},
+	})
+	b.SetPluralRule("en", func(n int) string {
+		if n == 2 {
+			return "two"
+		}
+		return english(n)
+	})
+	if v := b.T("en", "apples", 2); v != "a pair of apples" {
+		t.Errorf("expected the custom rule to be used got %s", v)
+	}
+}
@@ -0,0 +1,113 @@
+package i18n
+
+// PluralRule returns the plural category of the count n, one of zero, one,
+// two, few, many and other.
+type PluralRule func(n int) string
+
+// pluralRules are the built in rules by language, simplified from the CLDR
+// rules for integer counts. Languages which are not listed use the English
+// rule.
+var pluralRules = map[string]PluralRule{
+	"ar": arabic,
+	"cs": czech,
+	"sk": czech,
+	"fr": french,
+	"pl": polish,
+	"ru": russian,
+	"uk": russian,
+	"be": russian,
+	"ja": noPlural,
+	"ko": noPlural,
+	"zh": noPlural,
+	"th": noPlural,
+	"vi": noPlural,
+	"id": noPlural,
+}
+
+// SetPluralRule sets the plural rule of lang, a language like en or a locale
+// like pt-BR. It overrides the built in rule.
+func (b *Bundle) SetPluralRule(lang string, rule PluralRule) {
+	b.rules[lang] = rule
+}
+
+// pluralRule returns the plural rule of locale.
+func (b *Bundle) pluralRule(locale string) PluralRule {
+	for _, l := range []string{locale, language(locale)} {
+		if r, ok := b.rules[l]; ok {
+			return r
+		}
+		if r, ok := pluralRules[l]; ok {
+			return r
+		}
+	}
+	return english
+}
+
+func english(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func french(n int) string {
+	if n == 0 || n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func noPlural(n int) string {
+	return "other"
+}
+
+func czech(n int) string {
+	switch {
+	case n == 1:
+		return "one"
+	case n >= 2 && n <= 4:
+		return "few"
+	}
+	return "other"
+}
+
+func polish(n int) string {
+	switch {
+	case n == 1:
+		return "one"
+	case isFew(n):
+		return "few"
+	}
+	return "many"
+}
+
+func russian(n int) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return "one"
+	case isFew(n):
+		return "few"
+	}
+	return "many"
+}
+
+// isFew returns true for counts ending with 2, 3 or 4, except 12, 13 and 14.
+func isFew(n int) bool {
+	return n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14)
+}
+
+func arabic(n int) string {
+	switch {
+	case n == 0:
+		return "zero"
+	case n == 1:
+		return "one"
+	case n == 2:
+		return "two"
+	case n%100 >= 3 && n%100 <= 10:
+		return "few"
+	case n%100 >= 11:
+		return "many"
+	}
+	return "other"
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 )
 
 var logThis = NewDefaultLogger(os.Stdout)
@@ -17,34 +20,134 @@ type Logger interface {
 	Success(v ...interface{})
 }
 
+// Leveled is a Logger with levels and structured fields. Messages below the
+// minimum level are dropped. Success messages are logged at the info level.
+type Leveled interface {
+	Logger
+	Debug(v ...interface{})
+
+	// With returns a logger adding the key value pairs to every message, e.g.
+	// With("user", id).
+	With(keyvals ...interface{}) Leveled
+
+	// SetLevel changes the minimum level, of this logger and of the loggers
+	// returned by With.
+	SetLevel(level Level)
+}
+
+// With returns l with the key value pairs added to every message. Loggers which
+// are not Leveled are wrapped, the pairs are appended to their messages.
+func With(l Logger, keyvals ...interface{}) Logger {
+	if lv, ok := l.(Leveled); ok {
+		return lv.With(keyvals...)
+	}
+	return &fieldLogger{Logger: l, fields: keyvals}
+}
+
 // DefaultLogger is the default logger
 type DefaultLogger struct {
 	*log.Logger
+	level  *slog.LevelVar
+	fields []interface{}
 }
 
 // NewDefaultLogger returns a default logger writing to out
 func NewDefaultLogger(out io.Writer) Logger {
 	d := &DefaultLogger{}
 	d.Logger = log.New(out, "", log.LstdFlags)
+	d.level = new(slog.LevelVar)
 	return d
 }
 
+// Debug logs debug messages, they are dropped unless the level is LevelDebug.
+func (d *DefaultLogger) Debug(v ...interface{}) {
+	d.log(LevelDebug, ">>DEBUG>>", v)
+}
+
 // Info logs info messages
 func (d *DefaultLogger) Info(v ...interface{}) {
-	d.Println(fmt.Sprintf(">>INFO>> %s", fmt.Sprint(v...)))
+	d.log(LevelInfo, ">>INFO>>", v)
 }
 
 // Errors log error messages
 func (d *DefaultLogger) Errors(v ...interface{}) {
-	d.Println(fmt.Sprintf(">>ERR>> %s", fmt.Sprint(v...)))
+	d.log(LevelError, ">>ERR>>", v)
 }
 
 // Warn logs warning messages
 func (d *DefaultLogger) Warn(v ...interface{}) {
-	d.Println(fmt.Sprintf(">>WARN>> %s", fmt.Sprint(v...)))
+	d.log(LevelWarn, ">>WARN>>", v)
 }
 
 // Success logs success messages
 func (d *DefaultLogger) Success(v ...interface{}) {
-	d.Println(fmt.Sprintf(">>SUCC>> %s", fmt.Sprint(v...)))
+	d.log(LevelInfo, ">>SUCC>>", v)
+}
+
+// With returns a logger appending the key value pairs to the messages, in
+// logfmt.
+func (d *DefaultLogger) With(keyvals ...interface{}) Leveled {
+	if d.level == nil {
+		d.level = new(slog.LevelVar)
+	}
+	fields := make([]interface{}, 0, len(d.fields)+len(keyvals))
+	fields = append(fields, d.fields...)
+	fields = append(fields, keyvals...)
+	return &DefaultLogger{Logger: d.Logger, level: d.level, fields: fields}
+}
+
+// SetLevel sets the minimum level, it is LevelInfo by default.
+func (d *DefaultLogger) SetLevel(level Level) {
+	if d.level == nil {
+		d.level = new(slog.LevelVar)
+	}
+	d.level.Set(slog.Level(level))
+}
+
+func (d *DefaultLogger) log(level Level, prefix string, v []interface{}) {
+	min := LevelInfo
+	if d.level != nil {
+		min = Level(d.level.Level())
+	}
+	if level < min {
+		return
+	}
+	d.Println(prefix + " " + fmt.Sprint(v...) + formatFields(d.fields))
+}
+
+// fieldLogger adds fields to the messages of a Logger which is not Leveled.
+type fieldLogger struct {
+	Logger
+	fields []interface{}
+}
+
+func (f *fieldLogger) Info(v ...interface{})    { f.Logger.Info(f.append(v)...) }
+func (f *fieldLogger) Errors(v ...interface{})  { f.Logger.Errors(f.append(v)...) }
+func (f *fieldLogger) Warn(v ...interface{})    { f.Logger.Warn(f.append(v)...) }
+func (f *fieldLogger) Success(v ...interface{}) { f.Logger.Success(f.append(v)...) }
+
+func (f *fieldLogger) append(v []interface{}) []interface{} {
+	return []interface{}{fmt.Sprint(v...) + formatFields(f.fields)}
+}
+
+// formatFields formats the key value pairs in logfmt, with a leading space.
+func formatFields(keyvals []interface{}) string {
+	var b strings.Builder
+	for i := 0; i < len(keyvals); i += 2 {
+		key, value := "!BADKEY", keyvals[i]
+		if i+1 < len(keyvals) {
+			key, value = fmt.Sprint(keyvals[i]), keyvals[i+1]
+		}
+		b.WriteString(" " + key + "=" + logfmtValue(fmt.Sprint(value)))
+	}
+	return b.String()
+}
+
+// logfmtValue quotes s when it is empty or contains spaces, quotes or equal
+// signs.
+func logfmtValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
 }
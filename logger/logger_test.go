@@ -26,3 +26,69 @@ func TestDefaultLogger(t *testing.T) {
 		}
 	}
 }
+
+func TestLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewDefaultLogger(buf).(Leveled)
+	l.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("expected debug messages to be dropped got %s", buf)
+	}
+	l.SetLevel(LevelWarn)
+	l.Info("hidden")
+	l.Success("hidden")
+	l.Warn("shown")
+	l.Errors("shown")
+	if strings.Contains(buf.String(), "hidden") || strings.Count(buf.String(), "shown") != 2 {
+		t.Errorf("expected warnings and errors only got %s", buf)
+	}
+
+	for _, s := range []struct {
+		name  string
+		level Level
+	}{
+		{"debug", LevelDebug}, {"INFO", LevelInfo}, {"", LevelInfo}, {"warning", LevelWarn}, {"error", LevelError},
+	} {
+		level, err := ParseLevel(s.name)
+		if err != nil || level != s.level {
+			t.Errorf("%s: expected %v got %v %v", s.name, s.level, level, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestWith(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewDefaultLogger(buf).(Leveled)
+	req := l.With("user", 42, "path", "/a b")
+	req.With("status", 200).Info("done")
+	if !strings.Contains(buf.String(), `>>INFO>> done user=42 path="/a b" status=200`) {
+		t.Errorf("expected the fields got %s", buf)
+	}
+
+	// the level is shared.
+	l.SetLevel(LevelError)
+	buf.Reset()
+	req.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("expected the message to be dropped got %s", buf)
+	}
+
+	// loggers which are not Leveled get the fields in the message.
+	p := &plain{}
+	With(p, "user", 42, "odd").Warn("careful")
+	if p.msg != "careful user=42 !BADKEY=odd" {
+		t.Errorf("unexpected message %q", p.msg)
+	}
+}
+
+type plain struct {
+	msg string
+}
+
+func (p *plain) Info(v ...interface{})    {}
+func (p *plain) Errors(v ...interface{})  {}
+func (p *plain) Warn(v ...interface{})    { p.msg = v[0].(string) }
+func (p *plain) Success(v ...interface{}) {}
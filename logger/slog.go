@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Log formats.
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// Options are the settings of the logger returned by New.
+type Options struct {
+	// Level is the minimum level of the messages.
+	Level Level
+
+	// Format is text, the format of DefaultLogger, json or logfmt. It
+	// defaults to text.
+	Format string
+}
+
+// New returns a Leveled logger writing to out in the format of opts.
+func New(out io.Writer, opts Options) (Leveled, error) {
+	var l Leveled
+	switch opts.Format {
+	case "", FormatText:
+		l = NewDefaultLogger(out).(Leveled)
+	case FormatJSON:
+		l = FromSlog(slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	case FormatLogfmt:
+		l = FromSlog(slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	default:
+		return nil, fmt.Errorf("utron: unknown log format %q, supported are text, json and logfmt", opts.Format)
+	}
+	l.SetLevel(opts.Level)
+	return l, nil
+}
+
+// FromSlog returns a Leveled logger writing to l, so utron messages go to the
+// slog handler of the application. Messages are filtered by the level of the
+// handler, and by the level set with SetLevel, which is LevelDebug by
+// default. Success messages are logged at the info level with success=true.
+func FromSlog(l *slog.Logger) Leveled {
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelDebug)
+	return &slogLogger{l: l, level: level}
+}
+
+type slogLogger struct {
+	l     *slog.Logger
+	level *slog.LevelVar
+}
+
+func (s *slogLogger) Debug(v ...interface{})  { s.log(slog.LevelDebug, v) }
+func (s *slogLogger) Info(v ...interface{})   { s.log(slog.LevelInfo, v) }
+func (s *slogLogger) Errors(v ...interface{}) { s.log(slog.LevelError, v) }
+func (s *slogLogger) Warn(v ...interface{})   { s.log(slog.LevelWarn, v) }
+
+func (s *slogLogger) Success(v ...interface{}) {
+	s.log(slog.LevelInfo, v, "success", true)
+}
+
+func (s *slogLogger) With(keyvals ...interface{}) Leveled {
+	return &slogLogger{l: s.l.With(keyvals...), level: s.level}
+}
+
+func (s *slogLogger) SetLevel(level Level) {
+	s.level.Set(slog.Level(level))
+}
+
+func (s *slogLogger) log(level slog.Level, v []interface{}, keyvals ...interface{}) {
+	if level < s.level.Level() {
+		return
+	}
+	s.l.Log(context.Background(), level, fmt.Sprint(v...), keyvals...)
+}
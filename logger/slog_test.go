@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, Options{Format: FormatJSON, Level: LevelDebug})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.With("user", 42).Debug("hello")
+	var entry map[string]interface{}
+	if err = json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "DEBUG" || entry["msg"] != "hello" || entry["user"] != float64(42) {
+		t.Errorf("unexpected entry %v", entry)
+	}
+
+	buf.Reset()
+	l, err = New(buf, Options{Format: FormatLogfmt, Level: LevelWarn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hidden")
+	l.With("path", "/a b").Errors("failed")
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, `level=ERROR msg=failed path="/a b"`) {
+		t.Errorf("unexpected output %s", out)
+	}
+
+	buf.Reset()
+	l, err = New(buf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hello")
+	if !strings.Contains(buf.String(), ">>INFO>> hello") {
+		t.Errorf("expected the text format got %s", buf)
+	}
+	if _, err = New(buf, Options{Format: "xml"}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestFromSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := FromSlog(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	l.Debug("hidden by the handler")
+	l.Success("migrated")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "msg=migrated success=true") {
+		t.Errorf("unexpected output %s", buf)
+	}
+	buf.Reset()
+	l.SetLevel(LevelError)
+	l.Warn("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("expected the message to be dropped got %s", buf)
+	}
+}
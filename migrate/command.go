@@ -0,0 +1,67 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Usage describes the arguments of Run.
+const Usage = `usage: migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down [n]  roll back the last n migrations, n defaults to 1
+  status    list the migrations and whether they are applied
+`
+
+// Run runs the migration command in args, e.g. up, down 2 or status, and
+// reports the result to out. It is the implementation of utron migrate.
+func (m *Migrator) Run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+	switch args[0] {
+	case "up":
+		done, err := m.Up()
+		for _, mig := range done {
+			fmt.Fprintf(out, "applied %d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("utron: bad number of migrations %s", args[1])
+			}
+			steps = n
+		}
+		done, err := m.Down(steps)
+		for _, mig := range done {
+			fmt.Fprintf(out, "rolled back %d_%s\n", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		list, err := m.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range list {
+			at := "pending"
+			if s.Applied {
+				at = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("utron: unknown migrate command %s\n%s", args[0], Usage)
+}
@@ -0,0 +1 @@
+CREATE TABLE posts (id integer primary key, title varchar(255));
@@ -0,0 +1 @@
+INSERT INTO posts (id, title) VALUES (1, 'hello');
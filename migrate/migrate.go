@@ -0,0 +1,317 @@
+// Package migrate applies versioned database migrations. A migration is either
+// a pair of Go functions, or SQL files in a migrations directory named after
+// the version and the name of the migration
+//
+//	migrations/20180901120000_create_users.up.sql
+//	migrations/20180901120000_create_users.down.sql
+//
+// A migration without a down file or function can not be rolled back. The
+// applied migrations are recorded in a schema table, so each runs only once.
+// Every migration runs in its own transaction.
+//
+// A SQL file is executed as a single statement, MySQL connections need the
+// multiStatements=true parameter for files with several statements.
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DefaultTable is the name of the schema table when Migrator.Table is not set.
+const DefaultTable = "schema_migrations"
+
+var errNoDB = errors.New("utron: migrations need a database")
+
+// Func changes the database schema or data. tx is the transaction the
+// migration runs in.
+type Func func(tx *gorm.DB) error
+
+// Migration is a versioned change to the database.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      Func
+	Down    Func
+}
+
+// Status is the state of a migration.
+type Status struct {
+	*Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// schemaMigration is a row of the schema table.
+type schemaMigration struct {
+	Version   int64 `gorm:"primary_key;auto_increment:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	// DB is the database migrated, it must be set before applying migrations.
+	DB *gorm.DB
+
+	// Table is the name of the schema table, it defaults to DefaultTable.
+	Table string
+
+	migrations map[int64]*Migration
+}
+
+// New returns a Migrator for db. db can be nil, and set later.
+func New(db *gorm.DB) *Migrator {
+	return &Migrator{
+		DB:         db,
+		migrations: make(map[int64]*Migration),
+	}
+}
+
+// Add adds a migration written in Go. down can be nil when the migration can
+// not be rolled back.
+func (m *Migrator) Add(version int64, name string, up, down Func) error {
+	if up == nil {
+		return fmt.Errorf("utron: migration %d has no up function", version)
+	}
+	return m.add(&Migration{Version: version, Name: name, Up: up, Down: down})
+}
+
+func (m *Migrator) add(mig *Migration) error {
+	if mig.Version <= 0 {
+		return fmt.Errorf("utron: migration %s has no version", mig.Name)
+	}
+	if _, ok := m.migrations[mig.Version]; ok {
+		return fmt.Errorf("utron: duplicate migration version %d", mig.Version)
+	}
+	m.migrations[mig.Version] = mig
+	return nil
+}
+
+// LoadDir adds the SQL migrations found in dir.
+func (m *Migrator) LoadDir(dir string) error {
+	return m.Load(os.DirFS(dir))
+}
+
+// Load adds the SQL migrations at the root of fsys. Files not ending with .sql
+// are ignored, and .sql files without .up or .down before the extension are up
+// migrations.
+func (m *Migrator) Load(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+	found := make(map[int64]*Migration)
+	var versions []int64
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		version, name, down, err := parseFileName(e.Name())
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return err
+		}
+		mig, ok := found[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: name}
+			found[version] = mig
+			versions = append(versions, version)
+		}
+		if mig.Name != name {
+			return fmt.Errorf("utron: migration %d has files with different names %s and %s", version, mig.Name, name)
+		}
+		fn := sqlFunc(string(data))
+		if down {
+			mig.Down = fn
+		} else {
+			mig.Up = fn
+		}
+	}
+	for _, v := range versions {
+		mig := found[v]
+		if mig.Up == nil {
+			return fmt.Errorf("utron: migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		if err := m.add(mig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFileName returns the version and name of the migration file name, e.g.
+// 20180901120000_create_users.up.sql.
+func parseFileName(file string) (version int64, name string, down bool, err error) {
+	base := strings.TrimSuffix(file, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		base = strings.TrimSuffix(base, ".up")
+	case strings.HasSuffix(base, ".down"):
+		base = strings.TrimSuffix(base, ".down")
+		down = true
+	}
+	parts := strings.SplitN(base, "_", 2)
+	version, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", false, fmt.Errorf("utron: migration file %s does not start with a version", file)
+	}
+	if len(parts) > 1 {
+		name = parts[1]
+	}
+	return version, name, down, nil
+}
+
+// sqlFunc returns a Func executing query.
+func sqlFunc(query string) Func {
+	return func(tx *gorm.DB) error {
+		if strings.TrimSpace(query) == "" {
+			return nil
+		}
+		return tx.Exec(query).Error
+	}
+}
+
+// Migrations returns all migrations, ordered by version.
+func (m *Migrator) Migrations() []*Migration {
+	list := make([]*Migration, 0, len(m.migrations))
+	for _, v := range m.migrations {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+	return list
+}
+
+func (m *Migrator) table() string {
+	if m.Table != "" {
+		return m.Table
+	}
+	return DefaultTable
+}
+
+// applied returns the rows of the schema table by version, creating the table
+// when it does not exist.
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	if m.DB == nil {
+		return nil, errNoDB
+	}
+	if err := m.DB.Table(m.table()).AutoMigrate(&schemaMigration{}).Error; err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := m.DB.Table(m.table()).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// Status returns the state of all migrations, ordered by version.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var list []Status
+	for _, mig := range m.Migrations() {
+		s := Status{Migration: mig}
+		if r, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = r.AppliedAt
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Up applies the pending migrations in order of version, and returns the ones
+// applied. It stops at the first migration failing.
+func (m *Migrator) Up() ([]*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var done []*Migration
+	for _, mig := range m.Migrations() {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.run(mig.Up, func(tx *gorm.DB) error {
+			row := &schemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().UTC()}
+			return tx.Table(m.table()).Create(row).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("utron: migration %d_%s: %v", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, and returns the ones
+// rolled back.
+func (m *Migrator) Down(steps int) ([]*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	list := m.Migrations()
+	var done []*Migration
+	for i := len(list) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := list[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == nil {
+			return done, fmt.Errorf("utron: migration %d_%s can not be rolled back", mig.Version, mig.Name)
+		}
+		err := m.run(mig.Down, func(tx *gorm.DB) error {
+			return tx.Table(m.table()).Where("version = ?", mig.Version).Delete(&schemaMigration{}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("utron: migration %d_%s: %v", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// run runs fn and record in a transaction.
+func (m *Migrator) run(fn, record Func) (err error) {
+	tx := m.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
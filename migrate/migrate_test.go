@@ -0,0 +1,155 @@
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+type post struct {
+	ID    int
+	Title string
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.DB().SetMaxOpenConns(1)
+	return db
+}
+
+func TestMigrator(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	m := New(db)
+	if err := m.LoadDir("fixtures/migrations"); err != nil {
+		t.Fatal(err)
+	}
+	err := m.Add(20180903120000, "rename_hello", func(tx *gorm.DB) error {
+		return tx.Exec("UPDATE posts SET title = ? WHERE id = 1", "hello world").Error
+	}, func(tx *gorm.DB) error {
+		return tx.Exec("UPDATE posts SET title = ? WHERE id = 1", "hello").Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Add(20180903120000, "again", func(*gorm.DB) error { return nil }, nil); err == nil {
+		t.Error("expected an error for a duplicate version")
+	}
+	if len(m.Migrations()) != 3 {
+		t.Fatalf("expected 3 migrations got %d", len(m.Migrations()))
+	}
+
+	done, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 3 {
+		t.Errorf("expected 3 migrations applied got %d", len(done))
+	}
+	var p post
+	if err = db.Table("posts").First(&p).Error; err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "hello world" {
+		t.Errorf("expected hello world got %s", p.Title)
+	}
+	if done, _ = m.Up(); len(done) != 0 {
+		t.Errorf("expected migrations to run once")
+	}
+
+	done, err = m.Down(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 || done[0].Name != "rename_hello" {
+		t.Errorf("expected rename_hello to be rolled back got %v", done)
+	}
+	status, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := []bool{true, true, false}
+	for i, s := range status {
+		if s.Applied != applied[i] {
+			t.Errorf("%d: expected applied %v got %v", s.Version, applied[i], s.Applied)
+		}
+	}
+
+	// the seed migration has no down file.
+	if _, err = m.Down(2); err == nil {
+		t.Error("expected an error rolling back an irreversible migration")
+	}
+}
+
+func TestMigratorFailure(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	m := New(db)
+	_ = m.Add(1, "create", func(tx *gorm.DB) error {
+		return tx.Exec("CREATE TABLE posts (id integer primary key, title varchar(255))").Error
+	}, nil)
+	_ = m.Add(2, "broken", func(tx *gorm.DB) error {
+		if err := tx.Exec("INSERT INTO posts (id, title) VALUES (1, 'x')").Error; err != nil {
+			return err
+		}
+		return errors.New("broken")
+	}, nil)
+	done, err := m.Up()
+	if err == nil || !strings.Contains(err.Error(), "2_broken") {
+		t.Fatalf("expected the broken migration to fail got %v", err)
+	}
+	if len(done) != 1 {
+		t.Errorf("expected 1 migration applied got %d", len(done))
+	}
+	var count int
+	db.Table("posts").Count(&count)
+	if count != 0 {
+		t.Errorf("expected the failed migration to be rolled back")
+	}
+	status, _ := m.Status()
+	if status[1].Applied {
+		t.Error("expected the failed migration to be pending")
+	}
+
+	if _, err = New(nil).Up(); err != errNoDB {
+		t.Errorf("expected %v got %v", errNoDB, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	m := New(db)
+	if err := m.LoadDir("fixtures/migrations"); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := m.Run([]string{"up"}, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "applied 20180902120000_seed_posts") {
+		t.Errorf("expected the applied migrations got %s", out)
+	}
+	out.Reset()
+	if err := m.Run([]string{"status"}, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "create_posts") || strings.Contains(out.String(), "pending") {
+		t.Errorf("expected all migrations applied got %s", out)
+	}
+	for _, args := range [][]string{nil, {"sideways"}, {"down", "zero"}} {
+		if err := m.Run(args, out); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
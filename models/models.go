@@ -1,8 +1,11 @@
 package models
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/gernest/utron/config"
@@ -15,9 +18,14 @@ import (
 )
 
 // Model facilitate database interactions, supports postgres, mysql and foundation
+//
+// When read replicas are configured, queries made with the embedded DB are sent
+// to the replicas and everything else to the primary database. Use Primary for
+// queries which must see writes made just before.
 type Model struct {
-	models map[string]reflect.Value
-	isOpen bool
+	models  map[string]reflect.Value
+	isOpen  bool
+	primary *gorm.DB
 	*gorm.DB
 }
 
@@ -34,17 +42,86 @@ func (m *Model) IsOpen() bool {
 	return m.isOpen
 }
 
-// OpenWithConfig opens database connection with the settings found in cfg
+// OpenWithConfig opens database connection with the settings found in cfg. The
+// Database is the name of a gorm dialect, see config.Dialects, mysql, postgres
+// and sqlite3 are supported out of the box.
 func (m *Model) OpenWithConfig(cfg *config.Config) error {
-	db, err := gorm.Open(cfg.Database, cfg.DatabaseConn)
+	return m.Open(cfg.DatabaseConfig())
+}
+
+// Open opens the database connections with the settings of dc.
+func (m *Model) Open(dc config.DatabaseConfig) error {
+	if err := checkDialect(dc.Database); err != nil {
+		return err
+	}
+	primary, err := openPool(dc, dc.DatabaseConn)
+	if err != nil {
+		return err
+	}
+	if len(dc.Replicas) == 0 {
+		db, err := gorm.Open(dc.Database, primary)
+		if err != nil {
+			return err
+		}
+		m.DB = db
+		m.primary = db
+		m.isOpen = true
+		return nil
+	}
+	split := &splitDB{primary: primary}
+	for _, conn := range dc.Replicas {
+		replica, err := openPool(dc, conn)
+		if err != nil {
+			_ = split.Close()
+			return err
+		}
+		split.replicas = append(split.replicas, replica)
+	}
+	db, err := gorm.Open(dc.Database, split.open())
 	if err != nil {
+		_ = split.Close()
 		return err
 	}
 	m.DB = db
+	m.primary, err = gorm.Open(dc.Database, primary)
+	if err != nil {
+		_ = split.Close()
+		return err
+	}
 	m.isOpen = true
 	return nil
 }
 
+// WithDB returns a copy of m using db, e.g. a transaction, with the same
+// registered models.
+func (m *Model) WithDB(db *gorm.DB) *Model {
+	return &Model{
+		models:  m.models,
+		isOpen:  m.isOpen,
+		primary: db,
+		DB:      db,
+	}
+}
+
+// Close closes the database connections, it does nothing when they are not
+// open.
+func (m *Model) Close() error {
+	if !m.isOpen {
+		return nil
+	}
+	m.isOpen = false
+	return m.DB.Close()
+}
+
+// Primary returns the connection to the primary database. It is the embedded
+// DB when there are no read replicas.
+func (m *Model) Primary() *gorm.DB {
+	if m.primary == nil {
+		return m.DB
+	}
+	return m.primary
+}
+
 // Register adds the values to the models registry
 func (m *Model) Register(values ...interface{}) error {
 
@@ -71,10 +148,47 @@ func (m *Model) Register(values ...interface{}) error {
 	return nil
 }
 
-// AutoMigrateAll runs migrations for all the registered models
+// checkDialect returns an error if dialect is not a gorm dialect, or if its
+// driver is not registered. gorm opens the driver named like the dialect.
+func checkDialect(dialect string) error {
+	dialects := strings.Join(config.Dialects, ", ")
+	if dialect == "" {
+		return fmt.Errorf("utron: no database configured, set database to one of %s or set no_model", dialects)
+	}
+	known := false
+	for _, d := range config.Dialects {
+		known = known || d == dialect
+	}
+	if !known {
+		return fmt.Errorf("utron: unknown database dialect %q, supported are %s", dialect, dialects)
+	}
+	for _, d := range sql.Drivers() {
+		if d == dialect {
+			return nil
+		}
+	}
+	return fmt.Errorf("utron: no driver registered for the database dialect %s, import it, e.g. github.com/jinzhu/gorm/dialects/mssql", dialect)
+}
+
+// Registered returns the types of the registered models by name.
+func (m *Model) Registered() map[string]reflect.Type {
+	types := make(map[string]reflect.Type, len(m.models))
+	for k, v := range m.models {
+		types[k] = v.Type().Elem()
+	}
+	return types
+}
+
+// AutoMigrateAll runs migrations for all the registered models, in order of
+// their names.
 func (m *Model) AutoMigrateAll() {
-	for _, v := range m.models {
-		m.AutoMigrate(v.Interface())
+	names := make([]string, 0, len(m.models))
+	for k := range m.models {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		m.Primary().AutoMigrate(m.models[k].Interface())
 	}
 }
 func getTypName(typ reflect.Type) string {
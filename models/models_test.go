@@ -0,0 +1,68 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gernest/utron/config"
+)
+
+type Book struct {
+	ID    int
+	Title string
+}
+
+type Author struct {
+	ID   int
+	Name string
+}
+
+func TestRegister(t *testing.T) {
+	m := NewModel()
+	if err := m.Register(&Book{}, Author{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Register(&Book{}, "author"); err == nil {
+		t.Error("expected an error registering a string")
+	}
+	types := m.Registered()
+	expect := map[string]reflect.Type{
+		"Book":   reflect.TypeOf(Book{}),
+		"Author": reflect.TypeOf(Author{}),
+	}
+	if !reflect.DeepEqual(types, expect) {
+		t.Errorf("expected %v got %v", expect, types)
+	}
+
+	cfg := &config.Config{Database: "sqlite3", DatabaseConn: ":memory:"}
+	if err := m.OpenWithConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.DB.DB().SetMaxOpenConns(1)
+	m.AutoMigrateAll()
+	for _, v := range []interface{}{&Book{}, &Author{}} {
+		if !m.HasTable(v) {
+			t.Errorf("expected a table for %T", v)
+		}
+	}
+}
+
+func TestOpenWithConfig(t *testing.T) {
+	sample := []struct {
+		dialect, expect string
+	}{
+		{"", "no database configured"},
+		{"oracle", `unknown database dialect "oracle"`},
+		// a driver name which is not a gorm dialect.
+		{"ql", `unknown database dialect "ql"`},
+		{"mssql", "no driver registered for the database dialect mssql"},
+	}
+	for _, s := range sample {
+		err := NewModel().OpenWithConfig(&config.Config{Database: s.dialect})
+		if err == nil || !strings.Contains(err.Error(), s.expect) {
+			t.Errorf("%q: expected %s got %v", s.dialect, s.expect, err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gernest/utron/config"
+)
+
+// openPool opens a connection pool to conn with the limits of dc, and checks
+// the database is reachable.
+func openPool(dc config.DatabaseConfig, conn string) (*sql.DB, error) {
+	db, err := sql.Open(dc.Database, conn)
+	if err != nil {
+		return nil, err
+	}
+	if dc.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(dc.MaxOpenConns)
+	}
+	if dc.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(dc.MaxIdleConns)
+	}
+	if dc.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(time.Duration(dc.ConnMaxLifetime) * time.Second)
+	}
+	if dc.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(time.Duration(dc.ConnMaxIdleTime) * time.Second)
+	}
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// splitDB sends SELECT queries to the replicas in turn, and every other
+// statement to the primary. Transactions always run on the primary.
+//
+// It is a driver.Connector, so the Model gets a *sql.DB routing the queries,
+// and DB() of the embedded gorm.DB works as without replicas. The connections
+// of that *sql.DB are light, the queries run on the pools of the primary and
+// the replicas.
+type splitDB struct {
+	primary  *sql.DB
+	replicas []*sql.DB
+	next     uint32
+}
+
+// open returns the *sql.DB routing the queries. Closing it closes the primary
+// and the replicas.
+func (s *splitDB) open() *sql.DB {
+	return sql.OpenDB(s)
+}
+
+func (s *splitDB) Connect(context.Context) (driver.Conn, error) {
+	return &splitConn{split: s}, nil
+}
+
+func (s *splitDB) Driver() driver.Driver {
+	return s.primary.Driver()
+}
+
+// Close closes the primary and the replicas.
+func (s *splitDB) Close() error {
+	err := s.primary.Close()
+	for _, r := range s.replicas {
+		if cerr := r.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// pick returns the database query is sent to. Inserts returning values, e.g.
+// INSERT ... RETURNING id, and locking reads go to the primary.
+func (s *splitDB) pick(query string) *sql.DB {
+	if len(s.replicas) == 0 || !isRead(query) {
+		return s.primary
+	}
+	n := atomic.AddUint32(&s.next, 1)
+	return s.replicas[int(n)%len(s.replicas)]
+}
+
+// isRead returns true if query only reads data.
+func isRead(query string) bool {
+	q := strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(q, "SELECT") {
+		return false
+	}
+	return !strings.Contains(q, "FOR UPDATE") && !strings.Contains(q, "FOR SHARE")
+}
+
+// splitConn is a connection of the *sql.DB of a splitDB. Queries run on the
+// transaction of the connection when there is one, otherwise on the database
+// picked by the splitDB.
+type splitConn struct {
+	split *splitDB
+	tx    *sql.Tx
+}
+
+func (c *splitConn) Prepare(query string) (driver.Stmt, error) {
+	return &splitStmt{conn: c, query: query}, nil
+}
+
+func (c *splitConn) Close() error {
+	if c.tx != nil {
+		_ = c.tx.Rollback()
+		c.tx = nil
+	}
+	return nil
+}
+
+func (c *splitConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *splitConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.split.primary.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.IsolationLevel(opts.Isolation),
+		ReadOnly:  opts.ReadOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.tx = tx
+	return splitTx{c}, nil
+}
+
+// CheckNamedValue accepts every argument, they are converted by the driver of
+// the database running the query.
+func (c *splitConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *splitConn) Ping(ctx context.Context) error {
+	return c.split.primary.PingContext(ctx)
+}
+
+func (c *splitConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.tx != nil {
+		return c.tx.ExecContext(ctx, query, namedArgs(args)...)
+	}
+	return c.split.primary.ExecContext(ctx, query, namedArgs(args)...)
+}
+
+func (c *splitConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var rows *sql.Rows
+	var err error
+	if c.tx != nil {
+		rows, err = c.tx.QueryContext(ctx, query, namedArgs(args)...)
+	} else {
+		rows, err = c.split.pick(query).QueryContext(ctx, query, namedArgs(args)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &splitRows{rows: rows}, nil
+}
+
+// namedArgs converts args back to the arguments of database/sql.
+func namedArgs(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			values[i] = sql.Named(a.Name, a.Value)
+			continue
+		}
+		values[i] = a.Value
+	}
+	return values
+}
+
+type splitTx struct {
+	conn *splitConn
+}
+
+func (t splitTx) Commit() error {
+	tx := t.conn.tx
+	t.conn.tx = nil
+	return tx.Commit()
+}
+
+func (t splitTx) Rollback() error {
+	tx := t.conn.tx
+	t.conn.tx = nil
+	return tx.Rollback()
+}
+
+// splitStmt is a prepared statement of a splitConn, it is run as a query of the
+// connection.
+type splitStmt struct {
+	conn  *splitConn
+	query string
+}
+
+func (s *splitStmt) Close() error {
+	return nil
+}
+
+func (s *splitStmt) NumInput() int {
+	return -1
+}
+
+func (s *splitStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valueArgs(args))
+}
+
+func (s *splitStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valueArgs(args))
+}
+
+func (s *splitStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *splitStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func valueArgs(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// splitRows returns the rows of a query made with database/sql to the
+// *sql.DB of a splitDB.
+type splitRows struct {
+	rows    *sql.Rows
+	columns []string
+}
+
+func (r *splitRows) Columns() []string {
+	if r.columns == nil {
+		r.columns, _ = r.rows.Columns()
+	}
+	return r.columns
+}
+
+func (r *splitRows) Close() error {
+	return r.rows.Close()
+}
+
+func (r *splitRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	// scanning into *interface{} copies the bytes, the buffers of the driver
+	// are reused by the next row.
+	values := make([]interface{}, len(dest))
+	ptrs := make([]interface{}, len(dest))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := r.rows.Scan(ptrs...); err != nil {
+		return err
+	}
+	for i, v := range values {
+		dest[i] = v
+	}
+	return nil
+}
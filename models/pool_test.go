@@ -0,0 +1,134 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gernest/utron/config"
+)
+
+func TestIsRead(t *testing.T) {
+	sample := []struct {
+		query string
+		read  bool
+	}{
+		{"SELECT * FROM books", true},
+		{"  select count(*) from books", true},
+		{"SELECT * FROM books FOR UPDATE", false},
+		{`INSERT INTO "books" ("title") VALUES ($1) RETURNING "books"."id"`, false},
+		{"UPDATE books SET title = ?", false},
+		{"DELETE FROM books", false},
+	}
+	for _, s := range sample {
+		if v := isRead(s.query); v != s.read {
+			t.Errorf("%s: expected %v got %v", s.query, s.read, v)
+		}
+	}
+}
+
+func TestReplicas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utron-replicas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dc := config.DatabaseConfig{
+		Database:     "sqlite3",
+		DatabaseConn: filepath.Join(dir, "primary.db"),
+		Replicas:     []string{filepath.Join(dir, "replica.db")},
+		MaxOpenConns: 3,
+		MaxIdleConns: 2,
+	}
+	m := NewModel()
+	if err = m.Open(dc); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if n := m.Primary().DB().Stats().MaxOpenConnections; n != 3 {
+		t.Errorf("expected 3 max open connections got %d", n)
+	}
+	if m.Primary() == m.DB {
+		t.Fatal("expected a separate primary connection")
+	}
+	// the embedded DB has a *sql.DB, as without replicas.
+	if m.DB.DB() == nil {
+		t.Fatal("expected the *sql.DB of the embedded DB")
+	}
+	if err = m.DB.DB().Ping(); err != nil {
+		t.Fatal(err)
+	}
+	m.DB.DB().SetMaxOpenConns(4)
+
+	// the replica is not really replicating, so reads show which database is
+	// used.
+	replica := NewModel()
+	if err = replica.Open(config.DatabaseConfig{Database: "sqlite3", DatabaseConn: dc.Replicas[0]}); err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+	replica.AutoMigrate(&Book{})
+	if err = replica.Create(&Book{Title: "on the replica"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	m.Primary().AutoMigrate(&Book{})
+	if err = m.Create(&Book{Title: "on the primary"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var b Book
+	if err = m.First(&b).Error; err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "on the replica" {
+		t.Errorf("expected the read from the replica got %s", b.Title)
+	}
+	var titles []string
+	if err = m.Model(&Book{}).Where("title <> ?", "").Pluck("title", &titles).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(titles) != 1 || titles[0] != "on the replica" {
+		t.Errorf("expected the rows of the replica got %v", titles)
+	}
+	b = Book{}
+	if err = m.Primary().First(&b).Error; err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "on the primary" {
+		t.Errorf("expected the read from the primary got %s", b.Title)
+	}
+
+	// transactions run on the primary.
+	tx := m.Begin()
+	b = Book{}
+	if err = tx.First(&b).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Create(&Book{Title: "rolled back"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if b.Title != "on the primary" {
+		t.Errorf("expected the transaction on the primary got %s", b.Title)
+	}
+	var count int
+	m.Primary().Model(&Book{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected the transaction to be rolled back got %d books", count)
+	}
+
+	// closing the Model closes the primary.
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Primary().DB().Ping(); err == nil {
+		t.Error("expected the primary to be closed")
+	}
+
+	dc.Replicas = []string{filepath.Join(dir, "missing", "replica.db")}
+	if err = NewModel().Open(dc); err == nil {
+		t.Error("expected an error for an unreachable replica")
+	}
+}
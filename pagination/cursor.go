@@ -0,0 +1,146 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidCursor is returned by PaginateCursor when the cursor of the request
+// was not made for the query.
+var ErrInvalidCursor = errors.New("utron: invalid pagination cursor")
+
+// PaginateCursor loads into dst, which is a pointer to a slice of models, the
+// items following the After cursor. Unlike Paginate, the items are neither
+// counted nor skipped with an offset, so the pages of large tables are as fast
+// to load as the first one.
+//
+// key is a unique column, usually the primary key. Items are sorted by the
+// first sort column of the request, if any, then by key in the same direction.
+// The pager links to the next page only.
+func (p *Params) PaginateCursor(db *gorm.DB, dst interface{}, key string) (*Pager, error) {
+	slice := reflect.ValueOf(dst)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("utron: paginating into %T, a pointer to a slice is expected", dst)
+	}
+	slice = slice.Elem()
+	cols := p.cursorColumns(key)
+	q := p.Filter(db)
+	if p.After != "" {
+		values, err := decodeCursor(db, slice.Type().Elem(), cols, p.After)
+		if err != nil {
+			return nil, err
+		}
+		sql, args := keyset(db, cols, values)
+		q = q.Where(sql, args...)
+	}
+	for _, c := range cols {
+		q = q.Order(order(db, c.Field, c.Desc))
+	}
+	if err := q.Limit(p.PerPage + 1).Find(dst).Error; err != nil {
+		return nil, err
+	}
+	pager := &Pager{PerPage: p.PerPage, Cursor: true, params: p}
+	if slice.Len() > p.PerPage {
+		slice.Set(slice.Slice(0, p.PerPage))
+		next, err := encodeCursor(db, slice.Index(p.PerPage-1), cols)
+		if err != nil {
+			return nil, err
+		}
+		pager.NextCursor = next
+	}
+	return pager, nil
+}
+
+// cursorColumns returns the columns the items are sorted by for cursor
+// pagination.
+func (p *Params) cursorColumns(key string) []Sort {
+	if len(p.Sort) == 0 {
+		return []Sort{{Field: key}}
+	}
+	first := p.Sort[0]
+	if first.Field == key {
+		return []Sort{first}
+	}
+	return []Sort{first, {Field: key, Desc: first.Desc}}
+}
+
+// keyset returns the condition selecting the items after values, e.g.
+//
+//	a > ? OR (a = ? AND b > ?)
+func keyset(db *gorm.DB, cols []Sort, values []interface{}) (string, []interface{}) {
+	var or []string
+	var args []interface{}
+	for i, c := range cols {
+		var and []string
+		for j := 0; j < i; j++ {
+			and = append(and, db.Dialect().Quote(cols[j].Field)+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if c.Desc {
+			op = " < ?"
+		}
+		and = append(and, db.Dialect().Quote(c.Field)+op)
+		args = append(args, values[i])
+		or = append(or, "("+strings.Join(and, " AND ")+")")
+	}
+	return strings.Join(or, " OR "), args
+}
+
+// encodeCursor returns the cursor of the model value v, made of the values of
+// cols.
+func encodeCursor(db *gorm.DB, v reflect.Value, cols []Sort) (string, error) {
+	if v.Kind() != reflect.Ptr {
+		v = v.Addr()
+	}
+	scope := db.NewScope(v.Interface())
+	values := make([]interface{}, len(cols))
+	for i, c := range cols {
+		f, ok := scope.FieldByName(c.Field)
+		if !ok {
+			return "", fmt.Errorf("utron: no column %s in %s", c.Field, v.Type().Elem())
+		}
+		values[i] = f.Field.Interface()
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor returns the values of cols in cursor, with the types of the
+// fields of the models of type typ.
+func decodeCursor(db *gorm.DB, typ reflect.Type, cols []Sort, cursor string) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var raw []json.RawMessage
+	if err = json.Unmarshal(b, &raw); err != nil || len(raw) != len(cols) {
+		return nil, ErrInvalidCursor
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	scope := db.NewScope(reflect.New(typ).Interface())
+	values := make([]interface{}, len(cols))
+	for i, c := range cols {
+		f, ok := scope.FieldByName(c.Field)
+		if !ok {
+			return nil, fmt.Errorf("utron: no column %s in %s", c.Field, typ)
+		}
+		v := reflect.New(f.Field.Type())
+		if err = json.Unmarshal(raw[i], v.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = v.Elem().Interface()
+	}
+	return values, nil
+}
@@ -0,0 +1,53 @@
+package pagination
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPaginateCursor(t *testing.T) {
+	db := newDB(t, 10)
+	for _, query := range []string{"", "sort=-price"} {
+		var all []Item
+		p := parse(t, query, opts)
+		after := ""
+		for pages := 0; ; pages++ {
+			if pages > 4 {
+				t.Fatalf("%s: expected 4 pages", query)
+			}
+			p.After = after
+			var items []Item
+			pager, err := p.PaginateCursor(db.DB, &items, "id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) > 3 {
+				t.Fatalf("expected at most 3 items got %d", len(items))
+			}
+			all = append(all, items...)
+			if !pager.HasNext() {
+				break
+			}
+			u, _ := url.Parse(pager.NextURL())
+			after = u.Query().Get("after")
+		}
+		if len(all) != 10 {
+			t.Fatalf("%s: expected all the items got %d", query, len(all))
+		}
+		for i := 1; i < len(all); i++ {
+			a, b := all[i-1], all[i]
+			if query == "" && a.ID >= b.ID {
+				t.Errorf("expected the items sorted by id got %v", all)
+			}
+			if query != "" && (a.Price < b.Price || a.Price == b.Price && a.ID <= b.ID) {
+				t.Errorf("expected the items sorted by price desc then id got %v", all)
+			}
+		}
+	}
+
+	p := parse(t, "after=bogus", opts)
+	var items []Item
+	if _, err := p.PaginateCursor(db.DB, &items, "id"); err != ErrInvalidCursor {
+		t.Errorf("expected %v got %v", ErrInvalidCursor, err)
+	}
+}
@@ -0,0 +1,153 @@
+package pagination
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gernest/utron/base"
+)
+
+// Pager describes a page of items, for templates, headers and JSON responses.
+//
+// Pagers of cursor pagination do not know the total of items nor the number of
+// pages, they only link to the next page.
+type Pager struct {
+	Page    int
+	PerPage int
+	Pages   int
+	Total   int
+
+	// Cursor is true for cursor pagination, NextCursor is then the cursor of
+	// the next page, which is empty on the last page.
+	Cursor     bool
+	NextCursor string
+
+	params *Params
+}
+
+// Link is a page in the list of pages of a Pager. Gap is true for the
+// separator between pages which are not next to each other.
+type Link struct {
+	Number  int
+	URL     string
+	Current bool
+	Gap     bool
+}
+
+// HasPrev returns true if there is a page before this one.
+func (p *Pager) HasPrev() bool {
+	return !p.Cursor && p.Page > 1
+}
+
+// HasNext returns true if there is a page after this one.
+func (p *Pager) HasNext() bool {
+	if p.Cursor {
+		return p.NextCursor != ""
+	}
+	return p.Page < p.Pages
+}
+
+// PageURL returns the URL of page n.
+func (p *Pager) PageURL(n int) string {
+	return p.params.URL("page", strconv.Itoa(n))
+}
+
+// PrevURL returns the URL of the previous page, if any.
+func (p *Pager) PrevURL() string {
+	if !p.HasPrev() {
+		return ""
+	}
+	return p.PageURL(p.Page - 1)
+}
+
+// NextURL returns the URL of the next page, if any.
+func (p *Pager) NextURL() string {
+	if !p.HasNext() {
+		return ""
+	}
+	if p.Cursor {
+		return p.params.URL("after", p.NextCursor, "page", "")
+	}
+	return p.PageURL(p.Page + 1)
+}
+
+// FirstURL returns the URL of the first page.
+func (p *Pager) FirstURL() string {
+	if p.Cursor {
+		return p.params.URL("after", "", "page", "")
+	}
+	return p.PageURL(1)
+}
+
+// LastURL returns the URL of the last page, it is empty for cursor pagination.
+func (p *Pager) LastURL() string {
+	if p.Cursor || p.Pages == 0 {
+		return ""
+	}
+	return p.PageURL(p.Pages)
+}
+
+// Links returns the first and the last pages, and the pages up to window pages
+// away from the current one, e.g. 1 … 4 5 [6] 7 8 … 20 for a window of 2.
+func (p *Pager) Links(window int) []Link {
+	var links []Link
+	last := 0
+	for n := 1; n <= p.Pages; n++ {
+		if n != 1 && n != p.Pages && (n < p.Page-window || n > p.Page+window) {
+			continue
+		}
+		if last != 0 && n > last+1 {
+			links = append(links, Link{Gap: true})
+		}
+		links = append(links, Link{Number: n, URL: p.PageURL(n), Current: n == p.Page})
+		last = n
+	}
+	return links
+}
+
+// LinkHeader returns the value of the Link header of the page, as described
+// in RFC 8288, e.g. </books?page=3>; rel="next".
+func (p *Pager) LinkHeader() string {
+	var links []string
+	add := func(u, rel string) {
+		if u != "" {
+			links = append(links, fmt.Sprintf("<%s>; rel=%q", u, rel))
+		}
+	}
+	add(p.FirstURL(), "first")
+	add(p.PrevURL(), "prev")
+	add(p.NextURL(), "next")
+	add(p.LastURL(), "last")
+	return strings.Join(links, ", ")
+}
+
+// SetHeaders sets the Link header of the response of ctx, and the X-Total-Count
+// header when the total is known.
+func (p *Pager) SetHeaders(ctx *base.Context) {
+	if h := p.LinkHeader(); h != "" {
+		ctx.SetHeader("Link", h)
+	}
+	if !p.Cursor {
+		ctx.SetHeader("X-Total-Count", strconv.Itoa(p.Total))
+	}
+}
+
+// Meta returns the pagination metadata for JSON responses.
+func (p *Pager) Meta() map[string]interface{} {
+	m := map[string]interface{}{"per_page": p.PerPage}
+	if p.Cursor {
+		m["next_cursor"] = p.NextCursor
+	} else {
+		m["page"] = p.Page
+		m["pages"] = p.Pages
+		m["total"] = p.Total
+	}
+	if u := p.NextURL(); u != "" {
+		m["next"] = u
+	}
+	if u := p.PrevURL(); u != "" {
+		m["prev"] = u
+	}
+	return m
+}
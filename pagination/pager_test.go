@@ -0,0 +1,50 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gernest/utron/base"
+)
+
+func TestPager(t *testing.T) {
+	p := parse(t, "page=5&sort=name", opts)
+	pager := &Pager{Page: 5, PerPage: 3, Pages: 9, Total: 27, params: p}
+	if pager.PrevURL() != "/items?page=4&sort=name" || pager.NextURL() != "/items?page=6&sort=name" {
+		t.Errorf("unexpected links %s %s", pager.PrevURL(), pager.NextURL())
+	}
+
+	var numbers []int
+	for _, l := range pager.Links(1) {
+		if l.Gap {
+			numbers = append(numbers, 0)
+		} else {
+			numbers = append(numbers, l.Number)
+		}
+	}
+	if !reflect.DeepEqual(numbers, []int{1, 0, 4, 5, 6, 0, 9}) {
+		t.Errorf("unexpected pages %v", numbers)
+	}
+
+	w := httptest.NewRecorder()
+	ctx := base.NewContext(w, httptest.NewRequest("GET", "/items", nil))
+	pager.SetHeaders(ctx)
+	link := `</items?page=1&sort=name>; rel="first", </items?page=4&sort=name>; rel="prev", ` +
+		`</items?page=6&sort=name>; rel="next", </items?page=9&sort=name>; rel="last"`
+	if h := w.Header().Get("Link"); h != link {
+		t.Errorf("unexpected link header %s", h)
+	}
+	if w.Header().Get("X-Total-Count") != "27" {
+		t.Error("expected the total count header")
+	}
+	meta := pager.Meta()
+	if meta["total"] != 27 || meta["next"] != "/items?page=6&sort=name" {
+		t.Errorf("unexpected meta %v", meta)
+	}
+
+	first := &Pager{Page: 1, PerPage: 3, params: p}
+	if first.HasPrev() || first.HasNext() || first.LastURL() != "" || len(first.Links(2)) != 0 {
+		t.Error("expected no links for an empty list")
+	}
+}
@@ -0,0 +1,273 @@
+// Package pagination parses the page, sort and filters of list requests and
+// applies them to gorm queries.
+//
+// The query of a request is of the form
+//
+//	?page=2&per_page=50&sort=-created_at,title&author=gernest
+//
+// Only the fields listed in the Options can be sorted and filtered on, anything
+// else in the query is ignored. A list action is
+//
+//	func (b *Books) Index() {
+//		p := pagination.Parse(b.Ctx, pagination.Options{
+//			Sortable:   []string{"title", "created_at"},
+//			Filterable: []string{"author"},
+//		})
+//		var books []Book
+//		pager, err := p.Paginate(b.Ctx.DB.DB, &books)
+//		if err != nil {
+//			b.Ctx.Log.Errors(err)
+//			return
+//		}
+//		pager.SetHeaders(b.Ctx)
+//		b.Ctx.Data["Books"] = books
+//		b.Ctx.Data["Pager"] = pager
+//		b.Ctx.Template = "books/index"
+//	}
+//
+// Large tables are better paginated with a cursor, see PaginateCursor.
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gernest/utron/base"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// DefaultPerPage is the number of items per page when the request does not
+	// set one.
+	DefaultPerPage = 20
+
+	// DefaultMaxPerPage is the largest number of items per page a request can
+	// ask for.
+	DefaultMaxPerPage = 100
+)
+
+// Options are the settings used to parse a request.
+type Options struct {
+	// PerPage is the number of items per page. It defaults to DefaultPerPage.
+	PerPage int
+
+	// MaxPerPage is the largest number of items per page. It defaults to
+	// DefaultMaxPerPage.
+	MaxPerPage int
+
+	// Sortable are the columns the items can be sorted by.
+	Sortable []string
+
+	// Filterable are the columns the items can be filtered on, by equality.
+	Filterable []string
+
+	// DefaultSort is the order used when the request does not set one, e.g.
+	// -created_at. It does not have to be Sortable.
+	DefaultSort string
+}
+
+// Sort is a column items are sorted by.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// String returns the sort as it is written in the query, e.g. -title.
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Params are the pagination, sort and filters of a request.
+type Params struct {
+	Page    int
+	PerPage int
+	Sort    []Sort
+
+	// Filters are the values of the filterable columns, by column.
+	Filters map[string]string
+
+	// After is the cursor of the last item of the previous page, for cursor
+	// pagination.
+	After string
+
+	url *url.URL
+}
+
+// Parse returns the Params of the request of ctx.
+func Parse(ctx *base.Context, opts Options) *Params {
+	return ParseURL(ctx.Request().URL, opts)
+}
+
+// ParseURL returns the Params of the query of u. Invalid values are replaced
+// by the defaults, and columns which are not whitelisted are ignored.
+func ParseURL(u *url.URL, opts Options) *Params {
+	if opts.PerPage <= 0 {
+		opts.PerPage = DefaultPerPage
+	}
+	if opts.MaxPerPage <= 0 {
+		opts.MaxPerPage = DefaultMaxPerPage
+	}
+	query := u.Query()
+	p := &Params{
+		Page:    atoi(query.Get("page"), 1),
+		PerPage: atoi(query.Get("per_page"), opts.PerPage),
+		Filters: make(map[string]string),
+		After:   query.Get("after"),
+		url:     u,
+	}
+	if p.PerPage > opts.MaxPerPage {
+		p.PerPage = opts.MaxPerPage
+	}
+	for _, v := range strings.Split(query.Get("sort"), ",") {
+		s := parseSort(v)
+		if contains(opts.Sortable, s.Field) {
+			p.Sort = append(p.Sort, s)
+		}
+	}
+	if len(p.Sort) == 0 && opts.DefaultSort != "" {
+		for _, s := range strings.Split(opts.DefaultSort, ",") {
+			p.Sort = append(p.Sort, parseSort(s))
+		}
+	}
+	for _, f := range opts.Filterable {
+		if v := query.Get(f); v != "" {
+			p.Filters[f] = v
+		}
+	}
+	return p
+}
+
+// SortString returns the sort as it is written in the query, e.g.
+// -created_at,title.
+func (p *Params) SortString() string {
+	s := make([]string, len(p.Sort))
+	for i, v := range p.Sort {
+		s[i] = v.String()
+	}
+	return strings.Join(s, ",")
+}
+
+// Sorted returns asc or desc if the items are sorted by field, or an empty
+// string.
+func (p *Params) Sorted(field string) string {
+	for _, s := range p.Sort {
+		if s.Field == field {
+			if s.Desc {
+				return "desc"
+			}
+			return "asc"
+		}
+	}
+	return ""
+}
+
+// SortURL returns the URL sorting the items by field. When they are already
+// sorted by field in ascending order the URL sorts them in descending order.
+func (p *Params) SortURL(field string) string {
+	value := field
+	if p.Sorted(field) == "asc" {
+		value = "-" + field
+	}
+	return p.URL("sort", value, "page", "", "after", "")
+}
+
+// URL returns the URL of the request with the query values of pairs of keys
+// and values set. Keys with empty values are removed.
+func (p *Params) URL(pairs ...string) string {
+	u := *p.url
+	query := u.Query()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			query.Del(pairs[i])
+		} else {
+			query.Set(pairs[i], pairs[i+1])
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// Filter returns db with the filters applied.
+func (p *Params) Filter(db *gorm.DB) *gorm.DB {
+	for _, f := range sortedKeys(p.Filters) {
+		db = db.Where(fmt.Sprintf("%s = ?", db.Dialect().Quote(f)), p.Filters[f])
+	}
+	return db
+}
+
+// Order returns db with the sort applied.
+func (p *Params) Order(db *gorm.DB) *gorm.DB {
+	for _, s := range p.Sort {
+		db = db.Order(order(db, s.Field, s.Desc))
+	}
+	return db
+}
+
+// Apply returns db with the filters, the sort and the page applied.
+func (p *Params) Apply(db *gorm.DB) *gorm.DB {
+	db = p.Order(p.Filter(db))
+	return db.Offset((p.Page - 1) * p.PerPage).Limit(p.PerPage)
+}
+
+// Paginate loads the items of the page into dst, which is a pointer to a slice
+// of models. The items matching the filters are counted for the pager.
+func (p *Params) Paginate(db *gorm.DB, dst interface{}) (*Pager, error) {
+	pager := &Pager{Page: p.Page, PerPage: p.PerPage, params: p}
+	if err := p.Filter(db).Model(dst).Count(&pager.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := p.Apply(db).Find(dst).Error; err != nil {
+		return nil, err
+	}
+	pager.Pages = (pager.Total + pager.PerPage - 1) / pager.PerPage
+	return pager, nil
+}
+
+func order(db *gorm.DB, column string, desc bool) string {
+	o := db.Dialect().Quote(column)
+	if desc {
+		o += " DESC"
+	}
+	return o
+}
+
+func parseSort(s string) Sort {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		return Sort{Field: s[1:], Desc: true}
+	}
+	return Sort{Field: s}
+}
+
+// atoi returns s as a positive number, or def when s is not one.
+func atoi(s string, def int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return def
+	}
+	return n
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
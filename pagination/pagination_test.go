@@ -0,0 +1,95 @@
+package pagination
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/models"
+)
+
+type Item struct {
+	ID    int
+	Name  string
+	Kind  string
+	Price int
+}
+
+func newDB(t *testing.T, n int) *models.Model {
+	dir, err := ioutil.TempDir("", "utron-pagination")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	db := models.NewModel()
+	err = db.Open(config.DatabaseConfig{Database: "sqlite3", DatabaseConn: filepath.Join(dir, "items.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.AutoMigrate(&Item{})
+	kinds := []string{"book", "pen"}
+	for i := 1; i <= n; i++ {
+		db.Create(&Item{Name: fmt.Sprintf("item %02d", i), Kind: kinds[i%2], Price: i % 3})
+	}
+	return db
+}
+
+func parse(t *testing.T, query string, opts Options) *Params {
+	u, err := url.Parse("/items?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ParseURL(u, opts)
+}
+
+var opts = Options{
+	PerPage:    3,
+	MaxPerPage: 5,
+	Sortable:   []string{"name", "price"},
+	Filterable: []string{"kind"},
+}
+
+func TestParseURL(t *testing.T) {
+	p := parse(t, "page=2&per_page=50&sort=-price,id%3Bdrop,name&kind=pen&name=x", opts)
+	if p.Page != 2 || p.PerPage != 5 {
+		t.Errorf("expected page 2 of 5 items got %d of %d", p.Page, p.PerPage)
+	}
+	if s := p.SortString(); s != "-price,name" {
+		t.Errorf("expected only sortable columns got %s", s)
+	}
+	if len(p.Filters) != 1 || p.Filters["kind"] != "pen" {
+		t.Errorf("expected only filterable columns got %v", p.Filters)
+	}
+	if p.Sorted("price") != "desc" || p.Sorted("name") != "asc" || p.Sorted("kind") != "" {
+		t.Error("unexpected sort directions")
+	}
+	if u := p.SortURL("name"); u != "/items?kind=pen&name=x&per_page=50&sort=-name" {
+		t.Errorf("unexpected sort url %s", u)
+	}
+
+	p = parse(t, "page=-1&per_page=zero", Options{DefaultSort: "-id"})
+	if p.Page != 1 || p.PerPage != DefaultPerPage || p.SortString() != "-id" {
+		t.Errorf("expected the defaults got %+v", p)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	db := newDB(t, 10)
+	p := parse(t, "page=2&sort=-name&kind=book", opts)
+	var items []Item
+	pager, err := p.Paginate(db.DB, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pager.Total != 5 || pager.Pages != 2 || len(items) != 2 {
+		t.Fatalf("expected the last 2 of 5 books got %d of %d %v", len(items), pager.Total, items)
+	}
+	if items[0].Name != "item 04" || items[1].Name != "item 02" {
+		t.Errorf("expected the books sorted by name desc got %v", items)
+	}
+}
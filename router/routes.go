@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -13,9 +14,11 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/gernest/ita"
+	"github.com/gernest/utron/assets"
 	"github.com/gernest/utron/base"
 	"github.com/gernest/utron/config"
 	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/i18n"
 	"github.com/gernest/utron/logger"
 	"github.com/gernest/utron/models"
 	"github.com/gernest/utron/view"
@@ -45,10 +48,20 @@ type Router struct {
 //Options additional settings for the router.
 type Options struct {
 	Model        *models.Model
+	DBs          map[string]*models.Model
 	View         view.View
 	Config       *config.Config
 	Log          logger.Logger
 	SessionStore sessions.Store
+	I18n         *i18n.Bundle
+
+	// ConfigFunc returns the configuration of new requests. When it is set it
+	// is used instead of Config, so the configuration can be reloaded.
+	ConfigFunc func() *config.Config
+
+	// Transaction runs every request in a database transaction, see the
+	// Transaction middleware.
+	Transaction bool
 }
 
 // NewRouter returns a new Router, if app is passed then it is used
@@ -218,6 +231,9 @@ func (r *Router) Add(ctrlfn func() controller.Controller, middlewares ...interfa
 		if !found {
 			for _, rFile := range routes.inCtrl {
 				if rFile.fn == v.fn {
+					if rFile.ctrl == "" {
+						rFile.ctrl = v.ctrl
+					}
 					if err := r.add(rFile, ctrlfn, middlewares...); err != nil {
 						return err
 					}
@@ -323,8 +339,24 @@ func (r *Router) add(activeRoute *route, ctrlfn func() controller.Controller, mi
 	route := r.HandleFunc(activeRoute.pattern, func(w http.ResponseWriter, req *http.Request) {
 		ctx := base.NewContext(w, req)
 		r.prepareContext(ctx)
-		chain := chainMiddleware(ctx, m...)
+
+		// the panic is not recovered, it is only reported to the OnDone
+		// functions of the context.
+		handled := false
+		defer func() {
+			if handled {
+				ctx.Done(nil)
+			} else {
+				ctx.Done(errPanic)
+			}
+		}()
+		wares := m
+		if r.Options != nil && r.Options.Transaction {
+			wares = append([]*Middleware{{Type: CtxMiddleware, value: Transaction}}, m...)
+		}
+		chain := chainMiddleware(ctx, wares...)
 		chain.ThenFunc(r.wrapController(ctx, activeRoute.fn, ctrlfn())).ServeHTTP(w, req)
+		handled = true
 	})
 
 	// register methods if any
@@ -332,9 +364,29 @@ func (r *Router) add(activeRoute *route, ctrlfn func() controller.Controller, mi
 		route.Methods(activeRoute.methods...)
 
 	}
+
+	// routes are named Controller.Method, so URLs can be built for them.
+	if activeRoute.ctrl != "" {
+		route.Name(activeRoute.ctrl + "." + activeRoute.fn)
+	}
 	return nil
 }
 
+// URL returns the URL path of the route named name, which is of the form
+// Controller.Method. pairs are the values of the route variables, e.g.
+//	r.URL("Hello.World", "name", "gernest")
+func (r *Router) URL(name string, pairs ...string) (string, error) {
+	route := r.Get(name)
+	if route == nil {
+		return "", fmt.Errorf("utron: no route named %s", name)
+	}
+	u, err := route.URL(pairs...)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
 func chainMiddleware(ctx *base.Context, wares ...*Middleware) alice.Chain {
 	if len(wares) > 0 {
 		var m []alice.Constructor
@@ -353,18 +405,26 @@ func (r *Router) prepareContext(ctx *base.Context) {
 		if r.Options.View != nil {
 			ctx.Set(r.Options.View)
 		}
-		if r.Options.Config != nil {
+		if r.Options.ConfigFunc != nil {
+			ctx.Cfg = r.Options.ConfigFunc()
+		} else if r.Options.Config != nil {
 			ctx.Cfg = r.Options.Config
 		}
 		if r.Options.Model != nil {
 			ctx.DB = r.Options.Model
 		}
+		if r.Options.DBs != nil {
+			ctx.DBs = r.Options.DBs
+		}
 		if r.Options.Log != nil {
 			ctx.Log = r.Options.Log
 		}
 		if r.Options.SessionStore != nil {
 			ctx.SessionStore = r.Options.SessionStore
 		}
+		if r.Options.I18n != nil {
+			ctx.I18n = r.Options.I18n
+		}
 	}
 
 	// It is a good idea to ensure that a well prepared context always has the
@@ -377,6 +437,7 @@ func (r *Router) prepareContext(ctx *base.Context) {
 // executes the method fn on Controller ctrl, it sets context.
 func (r *Router) handleController(ctx *base.Context, fn string, ctrl controller.Controller) {
 	ctrl.New(ctx)
+	setLayout(ctx, ctrl)
 	// execute the method
 	// TODO: better error handling?
 	if x := ita.New(ctrl).Call(fn); x.Error() != nil {
@@ -386,9 +447,32 @@ func (r *Router) handleController(ctx *base.Context, fn string, ctrl controller.
 		_ = ctx.Commit()
 		return
 	}
-	err := ctx.Commit()
-	if err != nil {
-		//TODO:  Log error
+	if ctx.IsCommitted() {
+		return
+	}
+	if err := ctx.Commit(); err != nil {
+		ctx.Log.Errors(err)
+
+		// In development mode the error, e.g a template parse error, is shown in
+		// the browser.
+		msg := http.StatusText(http.StatusInternalServerError)
+		if ctx.Cfg != nil && ctx.Cfg.Dev {
+			msg = err.Error()
+		}
+		http.Error(ctx.Response(), msg, http.StatusInternalServerError)
+	}
+}
+
+// setLayout uses the value of the Layout field of ctrl, if there is any, as
+// the default layout for the templates rendered by ctrl.
+func setLayout(ctx *base.Context, ctrl controller.Controller) {
+	v := reflect.Indirect(reflect.ValueOf(ctrl))
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	f := v.FieldByName("Layout")
+	if f.IsValid() && f.Kind() == reflect.String && ctx.Layout == "" {
+		ctx.Layout = f.String()
 	}
 }
 
@@ -484,3 +568,15 @@ func (r *Router) LoadRoutes(cfgPath string) {
 func (r *Router) Static(prefix string, h http.FileSystem) {
 	r.PathPrefix(prefix).Handler(http.StripPrefix(prefix, http.FileServer(h)))
 }
+
+// Assets registers an asset manager serving the files of fsys for path prefix.
+// Unlike Static, files are fingerprinted, cached and compressed, see the assets
+// package. The returned Manager builds the paths of the files.
+func (r *Router) Assets(prefix string, fsys fs.FS, opts assets.Options) (*assets.Manager, error) {
+	m, err := assets.New(fsys, prefix, opts)
+	if err != nil {
+		return nil, err
+	}
+	r.PathPrefix(prefix).Handler(http.StripPrefix(prefix, m))
+	return m, nil
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/view"
 )
 
 var msg = "gernest"
@@ -156,3 +157,57 @@ func TestSplitRoutes(t *testing.T) {
 		}
 	}
 }
+
+type Pages struct {
+	controller.BaseController
+	Layout string
+}
+
+func (p *Pages) Two() {
+	p.Ctx.Data["Name"] = msg
+	p.Ctx.Template = "pages/two"
+	p.HTML(http.StatusOK)
+}
+
+func TestControllerLayout(t *testing.T) {
+	v, err := view.NewSimpleView("../fixtures/layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter(&Options{View: v})
+	_ = r.Add(controller.GetCtrlFunc(&Pages{Layout: "plain"}))
+
+	req, _ := http.NewRequest("GET", "/pages/two", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	expect := "plain:second " + msg
+	if w.Body.String() != expect {
+		t.Errorf("expected %s got %s", expect, w.Body.String())
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+	r := NewRouter()
+	s := &Sample{}
+	s.Routes = []string{"get;/hello/{name};Hello"}
+	_ = r.Add(controller.GetCtrlFunc(s))
+
+	u, err := r.URL("Sample.Hello", "name", "gernest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "/hello/gernest" {
+		t.Errorf("expected /hello/gernest got %s", u)
+	}
+	u, err = r.URL("Sample.Bang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "/sample/bang" {
+		t.Errorf("expected /sample/bang got %s", u)
+	}
+	if _, err = r.URL("Sample.Nope"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gernest/utron/base"
+)
+
+var (
+	errPanic = errors.New("utron: the handler panicked")
+	errNoDB  = errors.New("utron: transactions need an open database")
+)
+
+// Transaction is a CtxMiddleware running the request in a database
+// transaction. Ctx.DB is replaced by the transaction, so controllers use it
+// without changes.
+//
+// The transaction is committed when the response is committed with a 2xx or
+// 3xx status, and rolled back when the status is 4xx or 5xx, the handler fails
+// before committing a response, or panics. When the commit fails the client
+// gets an internal server error instead of the response.
+func Transaction(ctx *base.Context) error {
+	if ctx.DB == nil || !ctx.DB.IsOpen() {
+		ctx.Log.Errors(errNoDB)
+		http.Error(ctx.Response(), http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return errNoDB
+	}
+	tx := ctx.DB.Primary().Begin()
+	if tx.Error != nil {
+		ctx.Log.Errors(tx.Error)
+		http.Error(ctx.Response(), http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return tx.Error
+	}
+	db := ctx.DB
+	ctx.DB = db.WithDB(tx)
+	done := false
+	ctx.OnCommit(func(status int) error {
+		done = true
+		if status >= http.StatusBadRequest {
+			// the response is an error already, a failed rollback is only
+			// logged.
+			if err := tx.Rollback().Error; err != nil {
+				ctx.Log.Errors(err)
+			}
+			return nil
+		}
+		return tx.Commit().Error
+	})
+	ctx.OnDone(func(err error) {
+		if !done {
+			tx.Rollback()
+		}
+		ctx.DB = db
+	})
+	return nil
+}
@@ -0,0 +1,123 @@
+package router
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gernest/utron/config"
+	"github.com/gernest/utron/controller"
+	"github.com/gernest/utron/models"
+)
+
+type Entry struct {
+	ID   int
+	Name string
+}
+
+type Entries struct {
+	controller.BaseController
+}
+
+func (e *Entries) create(name string) {
+	e.Ctx.DB.Create(&Entry{Name: name})
+}
+
+func (e *Entries) Ok() {
+	e.create("ok")
+	e.String(http.StatusCreated)
+}
+
+func (e *Entries) Moved() {
+	e.create("moved")
+	e.Ctx.Redirect("/entries/ok", http.StatusFound)
+}
+
+func (e *Entries) Invalid() {
+	e.create("invalid")
+	e.String(http.StatusUnprocessableEntity)
+}
+
+func (e *Entries) Broken() {
+	e.create("broken")
+	e.Ctx.Template = "missing"
+	e.Ctx.Set(failingView{})
+}
+
+func (e *Entries) Panic() {
+	e.create("panic")
+	panic("boom")
+}
+
+type failingView struct{}
+
+func (failingView) Render(out io.Writer, name string, data interface{}) error {
+	return errors.New("render failed")
+}
+
+func TestTransaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utron-tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db := models.NewModel()
+	err = db.Open(config.DatabaseConfig{Database: "sqlite3", DatabaseConn: filepath.Join(dir, "tx.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.AutoMigrate(&Entry{})
+
+	r := NewRouter(&Options{Model: db, Transaction: true})
+	_ = r.Add(controller.GetCtrlFunc(&Entries{}))
+
+	sample := []struct {
+		path  string
+		saved bool
+	}{
+		{"/entries/ok", true},
+		{"/entries/moved", true},
+		{"/entries/invalid", false},
+		{"/entries/broken", false},
+		{"/entries/panic", false},
+	}
+	for _, s := range sample {
+		func() {
+			defer func() { _ = recover() }()
+			req, _ := http.NewRequest("GET", s.path, nil)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+		var count int
+		db.Model(&Entry{}).Where("name = ?", filepath.Base(s.path)).Count(&count)
+		if (count == 1) != s.saved {
+			t.Errorf("%s: expected saved %v got %d rows", s.path, s.saved, count)
+		}
+	}
+
+	// without a database the request fails.
+	r = NewRouter(&Options{Transaction: true})
+	_ = r.Add(controller.GetCtrlFunc(&Entries{}))
+	req, _ := http.NewRequest("GET", "/entries/ok", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	// the middleware can be used on its own.
+	r = NewRouter(&Options{Model: db})
+	_ = r.Add(controller.GetCtrlFunc(&Entries{}), Transaction)
+	req, _ = http.NewRequest("GET", "/entries/invalid", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	var count int
+	db.Model(&Entry{}).Where("name = ?", "invalid").Count(&count)
+	if count != 0 {
+		t.Error("expected the transaction to be rolled back")
+	}
+}
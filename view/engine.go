@@ -0,0 +1,153 @@
+package view
+
+import (
+	"html/template"
+	"io"
+	texttemplate "text/template"
+)
+
+// Engine is a template engine. SimpleView picks the engine of a template file
+// by its extension.
+type Engine interface {
+	// New returns an empty set of templates, funcs are the template functions
+	// for engines supporting them.
+	New(name string, funcs template.FuncMap) Templates
+}
+
+// Templates is a set of named templates which can refer to each other.
+type Templates interface {
+	// Parse parses text as the template named name. Layouts render the page
+	// using {{yield}}, which must execute the template named yield.
+	Parse(name, text string) error
+
+	// Alias defines the template name which executes the template target.
+	Alias(name, target string) error
+
+	// Clone returns a copy of the set. Templates parsed in the copy do not
+	// affect the original set.
+	Clone() (Templates, error)
+
+	// Lookup returns true if the set has a template named name.
+	Lookup(name string) bool
+
+	// Execute executes the template named name.
+	Execute(out io.Writer, name string, data interface{}) error
+}
+
+// DefaultEngines returns the engines used for file extensions when
+// Options.Engines is not set. The .tpl, .html and .tmpl files are HTML
+// templates, .txt files are text templates and .mustache files are Mustache
+// templates.
+func DefaultEngines() map[string]Engine {
+	return map[string]Engine{
+		".tpl":      HTMLEngine,
+		".html":     HTMLEngine,
+		".tmpl":     HTMLEngine,
+		".txt":      TextEngine,
+		".mustache": MustacheEngine,
+	}
+}
+
+var (
+	// HTMLEngine is the html/template engine. Output is escaped according to
+	// the context, so it is safe for HTML pages.
+	HTMLEngine Engine = htmlEngine{}
+
+	// TextEngine is the text/template engine. Output is not escaped, use it
+	// for emails and plain text.
+	TextEngine Engine = textEngine{}
+
+	// MustacheEngine implements logic-less Mustache templates.
+	MustacheEngine Engine = mustacheEngine{}
+
+	// engines are the built in engines by name.
+	engines = map[string]Engine{
+		"html":     HTMLEngine,
+		"text":     TextEngine,
+		"mustache": MustacheEngine,
+	}
+)
+
+// EngineByName returns a built in engine, the names are html, text and
+// mustache.
+func EngineByName(name string) (Engine, bool) {
+	e, ok := engines[name]
+	return e, ok
+}
+
+// rewriteYield replaces {{yield}} with the execution of the yield template.
+func rewriteYield(text string) string {
+	return yieldTag.ReplaceAllString(text, `{{$1 template "`+yieldName+`" . $2}}`)
+}
+
+type htmlEngine struct{}
+
+func (htmlEngine) New(name string, funcs template.FuncMap) Templates {
+	return &htmlTemplates{template.New(name).Funcs(funcs)}
+}
+
+type htmlTemplates struct {
+	t *template.Template
+}
+
+func (h *htmlTemplates) Parse(name, text string) error {
+	_, err := h.t.New(name).Parse(rewriteYield(text))
+	return err
+}
+
+func (h *htmlTemplates) Alias(name, target string) error {
+	_, err := h.t.New(name).Parse(`{{template "` + target + `" .}}`)
+	return err
+}
+
+func (h *htmlTemplates) Clone() (Templates, error) {
+	t, err := h.t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &htmlTemplates{t}, nil
+}
+
+func (h *htmlTemplates) Lookup(name string) bool {
+	return h.t.Lookup(name) != nil
+}
+
+func (h *htmlTemplates) Execute(out io.Writer, name string, data interface{}) error {
+	return h.t.ExecuteTemplate(out, name, data)
+}
+
+type textEngine struct{}
+
+func (textEngine) New(name string, funcs template.FuncMap) Templates {
+	return &textTemplates{texttemplate.New(name).Funcs(texttemplate.FuncMap(funcs))}
+}
+
+type textTemplates struct {
+	t *texttemplate.Template
+}
+
+func (x *textTemplates) Parse(name, text string) error {
+	_, err := x.t.New(name).Parse(rewriteYield(text))
+	return err
+}
+
+func (x *textTemplates) Alias(name, target string) error {
+	_, err := x.t.New(name).Parse(`{{template "` + target + `" .}}`)
+	return err
+}
+
+func (x *textTemplates) Clone() (Templates, error) {
+	t, err := x.t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &textTemplates{t}, nil
+}
+
+func (x *textTemplates) Lookup(name string) bool {
+	return x.t.Lookup(name) != nil
+}
+
+func (x *textTemplates) Execute(out io.Writer, name string, data interface{}) error {
+	return x.t.ExecuteTemplate(out, name, data)
+}
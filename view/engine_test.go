@@ -0,0 +1,100 @@
+package view
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Title string
+}
+
+func (i item) Upper() string {
+	return strings.ToUpper(i.Title)
+}
+
+func TestEngines(t *testing.T) {
+	v, err := NewSimpleView("../fixtures/engines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := map[string]string{"Name": "<b>gernest</b>"}
+	items := map[string]interface{}{
+		"Items": []item{{"a&b"}, {"c"}},
+	}
+	sample := []struct {
+		name   string
+		data   interface{}
+		expect string
+	}{
+		{"mail/welcome", name, "<p>Hello &lt;b&gt;gernest&lt;/b&gt;</p>"},
+		{"mail/welcome.html", name, "<p>Hello &lt;b&gt;gernest&lt;/b&gt;</p>"},
+		{"mail/welcome.txt", name, "Hello <b>gernest</b>"},
+		{"pages/list", items, "<main><li>a&amp;b</li><li>c</li></main>"},
+		{"pages/list", map[string]interface{}{}, "<main>none</main>"},
+	}
+	for _, s := range sample {
+		out := &bytes.Buffer{}
+		if err = v.Render(out, s.name, s.data); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if out.String() != s.expect {
+			t.Errorf("%s: expected %s got %s", s.name, s.expect, out.String())
+		}
+	}
+
+	// text templates everywhere
+	v, err = NewSimpleViewWithOptions("../fixtures/engines", Options{
+		Engines: map[string]Engine{".html": TextEngine},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err = v.Render(out, "mail/welcome", name); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "<p>Hello <b>gernest</b></p>" {
+		t.Errorf("expected unescaped output got %s", out.String())
+	}
+}
+
+func TestMustache(t *testing.T) {
+	sample := []struct {
+		tpl    string
+		data   interface{}
+		expect string
+	}{
+		{"hello {{Name}}", map[string]string{"Name": "<gernest>"}, "hello &lt;gernest&gt;"},
+		{"hello {{{Name}}} {{&Name}}", map[string]string{"Name": "<g>"}, "hello <g> <g>"},
+		{"{{! comment }}{{#Show}}yes{{/Show}}{{^Show}}no{{/Show}}", map[string]bool{"Show": false}, "no"},
+		{"{{#Items}}{{.}},{{/Items}}", map[string][]int{"Items": {1, 2}}, "1,2,"},
+		{"{{#Item}}{{Title}} {{Upper}}{{/Item}}", map[string]item{"Item": {"go"}}, "go GO"},
+		{"{{Item.Title}}", map[string]*item{"Item": {"go"}}, "go"},
+		{"{{#Item}}{{Name}}{{/Item}}", map[string]interface{}{"Name": "outer", "Item": item{}}, "outer"},
+		{"{{> part}}", map[string]string{"Name": "gernest"}, "part gernest"},
+	}
+	for _, s := range sample {
+		set := MustacheEngine.New("test", nil)
+		if err := set.Parse("part", "part {{Name}}"); err != nil {
+			t.Fatal(err)
+		}
+		if err := set.Parse("test", s.tpl); err != nil {
+			t.Fatalf("%s: %v", s.tpl, err)
+		}
+		out := &bytes.Buffer{}
+		if err := set.Execute(out, "test", s.data); err != nil {
+			t.Fatalf("%s: %v", s.tpl, err)
+		}
+		if out.String() != s.expect {
+			t.Errorf("%s: expected %s got %s", s.tpl, s.expect, out.String())
+		}
+	}
+
+	for _, bad := range []string{"{{#a}}", "{{/a}}", "{{a", "{{}}"} {
+		if err := MustacheEngine.New("test", nil).Parse("bad", bad); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
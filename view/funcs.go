@@ -0,0 +1,127 @@
+package view
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultFuncs returns the template functions available to every template
+// loaded by SimpleView.
+//
+//	date      formats a time, {{.Created | date "2006-01-02"}}
+//	now       returns the current time
+//	pluralize picks the word for a count, {{pluralize .Count "item" "items"}}
+//	truncate  shortens a string to n characters, {{.Body | truncate 100}}
+//	asset     returns the URL of a static file, {{asset "app.css"}}
+//	url       returns the URL of a route, {{url "Home.Index" "id" "1"}}
+//	t         translates a message, {{t .Locale "cart.items" .Count}}
+//	json      embeds a value as JSON, <script>var x = {{json .}}</script>
+//	safeHTML  marks a string as safe HTML, the same for safeAttr, safeURL,
+//	          safeJS and safeCSS
+//	dict      builds a map from key value pairs, {{template "row" dict "A" 1}}
+//	list      builds a slice from its arguments
+//
+// The asset function serves files under /static/, url always fails and t
+// returns the key, the App replaces them with functions bound to its static
+// server, router and message catalogs.
+func DefaultFuncs() template.FuncMap {
+	return template.FuncMap{
+		"date":      formatDate,
+		"now":       time.Now,
+		"pluralize": pluralize,
+		"truncate":  truncate,
+		"asset":     func(name string) string { return path.Join("/static", name) },
+		"url": func(name string, pairs ...string) (string, error) {
+			return "", fmt.Errorf("utron: no router to build url for %s", name)
+		},
+		"t": func(locale, key string, args ...interface{}) string {
+			return key
+		},
+		"json":     toJSON,
+		"safeHTML": func(s string) template.HTML { return template.HTML(s) },
+		"safeAttr": func(s string) template.HTMLAttr { return template.HTMLAttr(s) },
+		"safeURL":  func(s string) template.URL { return template.URL(s) },
+		"safeJS":   func(s string) template.JS { return template.JS(s) },
+		"safeCSS":  func(s string) template.CSS { return template.CSS(s) },
+		"dict":     dict,
+		"list":     list,
+	}
+}
+
+// formatDate formats t using layout. t can be a time.Time, *time.Time or a unix
+// timestamp.
+func formatDate(layout string, t interface{}) (string, error) {
+	switch v := t.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	case int64:
+		return time.Unix(v, 0).Format(layout), nil
+	case int:
+		return time.Unix(int64(v), 0).Format(layout), nil
+	}
+	return "", fmt.Errorf("utron: date can not format %T", t)
+}
+
+// pluralize returns singular when count is one, and plural otherwise.
+func pluralize(count int, singular, plural string) string {
+	if count == 1 || count == -1 {
+		return singular
+	}
+	return plural
+}
+
+// truncate shortens s to at most n characters, ending with an ellipsis when
+// anything was removed. It returns an empty string when n is not positive.
+func truncate(n int, s string) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string([]rune(s)[:n])
+	}
+	return strings.TrimSpace(string([]rune(s)[:n-3])) + "..."
+}
+
+// toJSON encodes v as JSON. The output is safe to embed in script elements.
+func toJSON(v interface{}) (template.JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(b), nil
+}
+
+// dict returns a map built from key value pairs.
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("utron: dict expects key value pairs")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("utron: dict key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// list returns its arguments as a slice.
+func list(v ...interface{}) []interface{} {
+	return v
+}
@@ -0,0 +1,70 @@
+package view
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+	"time"
+)
+
+func TestDefaultFuncs(t *testing.T) {
+	created := time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC)
+	sample := []struct {
+		tpl    string
+		data   interface{}
+		expect string
+	}{
+		{`{{. | date "2006-01-02"}}`, created, "2016-01-02"},
+		{`{{pluralize 1 "item" "items"}} {{pluralize 2 "item" "items"}}`, nil, "item items"},
+		{`{{. | truncate 8}}`, "hello gernest", "hello..."},
+		{`{{. | truncate 20}}`, "hello gernest", "hello gernest"},
+		{`{{truncate -1 .}}`, "hello gernest", ""},
+		{`{{truncate 0 .}}`, "hello gernest", ""},
+		{`{{asset "css/app.css"}}`, nil, "/static/css/app.css"},
+		{`<script>var v = {{json .}};</script>`, map[string]int{"a": 1}, `<script>var v = {"a":1};</script>`},
+		{`{{safeHTML .}}`, "<b>bold</b>", "<b>bold</b>"},
+		{`{{.}}`, "<b>bold</b>", "&lt;b&gt;bold&lt;/b&gt;"},
+		{`<a {{safeAttr .}}>`, `href="/"`, `<a href="/">`},
+		{`{{with dict "Name" "gernest"}}{{.Name}}{{end}}`, nil, "gernest"},
+		{`{{range list 1 2 3}}{{.}}{{end}}`, nil, "123"},
+	}
+	for _, s := range sample {
+		tpl, err := template.New("test").Funcs(DefaultFuncs()).Parse(s.tpl)
+		if err != nil {
+			t.Fatalf("%s: %v", s.tpl, err)
+		}
+		out := &bytes.Buffer{}
+		if err = tpl.Execute(out, s.data); err != nil {
+			t.Fatalf("%s: %v", s.tpl, err)
+		}
+		if out.String() != s.expect {
+			t.Errorf("%s: expected %s got %s", s.tpl, s.expect, out.String())
+		}
+	}
+
+	if _, err := dict("key"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSimpleViewFuncs(t *testing.T) {
+	v, err := NewSimpleViewWithOptions("../fixtures/view", Options{
+		Funcs: template.FuncMap{"greet": func() string { return "hi" }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := v.(*SimpleView)
+	err = s.Funcs(template.FuncMap{"asset": func(name string) string { return "/assets/" + name }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"greet", "asset", "date"} {
+		if _, ok := s.funcs[name]; !ok {
+			t.Errorf("expected %s to be registered", name)
+		}
+	}
+	if s.funcs["asset"].(func(string) string)("a.css") != "/assets/a.css" {
+		t.Error("expected asset to be replaced")
+	}
+}
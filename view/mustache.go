@@ -0,0 +1,318 @@
+package view
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// maxPartialDepth limits nesting of mustache partials, so a partial including
+// itself fails instead of exhausting the stack.
+const maxPartialDepth = 100
+
+// mustacheEngine implements the Mustache template language. Supported tags are
+// variables {{name}}, unescaped variables {{{name}}} and {{&name}}, sections
+// {{#name}}{{/name}}, inverted sections {{^name}}{{/name}}, partials
+// {{>name}} and comments {{!comment}}. Names can be dotted, and {{.}} is the
+// current item. In layouts {{yield}} renders the page.
+//
+// Template functions are not supported, mustache is logic-less.
+type mustacheEngine struct{}
+
+func (mustacheEngine) New(name string, funcs template.FuncMap) Templates {
+	return &mustacheTemplates{
+		tpls:  make(map[string][]mNode),
+		alias: make(map[string]string),
+	}
+}
+
+type mustacheTemplates struct {
+	tpls  map[string][]mNode
+	alias map[string]string
+}
+
+// mNode is an element of a parsed mustache template. It is one of mText,
+// *mVar, *mSection or mPartial.
+type mNode interface{}
+
+type mText string
+
+type mVar struct {
+	name   string
+	escape bool
+}
+
+type mSection struct {
+	name     string
+	inverted bool
+	nodes    []mNode
+}
+
+type mPartial string
+
+func (m *mustacheTemplates) Parse(name, text string) error {
+	nodes, err := parseMustache(text)
+	if err != nil {
+		return fmt.Errorf("utron: template %s: %v", name, err)
+	}
+	m.tpls[name] = nodes
+	return nil
+}
+
+func (m *mustacheTemplates) Alias(name, target string) error {
+	m.alias[name] = target
+	return nil
+}
+
+func (m *mustacheTemplates) Clone() (Templates, error) {
+	c := &mustacheTemplates{
+		tpls:  make(map[string][]mNode, len(m.tpls)),
+		alias: make(map[string]string, len(m.alias)),
+	}
+	for k, v := range m.tpls {
+		c.tpls[k] = v
+	}
+	for k, v := range m.alias {
+		c.alias[k] = v
+	}
+	return c, nil
+}
+
+func (m *mustacheTemplates) lookup(name string) ([]mNode, bool) {
+	if target, ok := m.alias[name]; ok {
+		name = target
+	}
+	nodes, ok := m.tpls[name]
+	return nodes, ok
+}
+
+func (m *mustacheTemplates) Lookup(name string) bool {
+	_, ok := m.lookup(name)
+	return ok
+}
+
+func (m *mustacheTemplates) Execute(out io.Writer, name string, data interface{}) error {
+	nodes, ok := m.lookup(name)
+	if !ok {
+		return fmt.Errorf("utron: no template %q", name)
+	}
+	return m.render(out, nodes, []interface{}{data}, 0)
+}
+
+// parseMustache parses text into a tree of nodes.
+func parseMustache(text string) ([]mNode, error) {
+	type frame struct {
+		section *mSection
+		nodes   []mNode
+	}
+	stack := []*frame{{}}
+	for len(text) > 0 {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			break
+		}
+		top := stack[len(stack)-1]
+		if start > 0 {
+			top.nodes = append(top.nodes, mText(text[:start]))
+		}
+		text = text[start+2:]
+
+		closer := "}}"
+		triple := strings.HasPrefix(text, "{")
+		if triple {
+			closer = "}}}"
+			text = text[1:]
+		}
+		end := strings.Index(text, closer)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed tag")
+		}
+		tag := strings.TrimSpace(text[:end])
+		text = text[end+len(closer):]
+
+		if triple {
+			top.nodes = append(top.nodes, mustacheVar(tag, false))
+			continue
+		}
+		if tag == "" {
+			return nil, fmt.Errorf("empty tag")
+		}
+		name := strings.TrimSpace(tag[1:])
+		switch tag[0] {
+		case '!':
+		case '&':
+			top.nodes = append(top.nodes, mustacheVar(name, false))
+		case '>':
+			top.nodes = append(top.nodes, mPartial(name))
+		case '#', '^':
+			s := &mSection{name: name, inverted: tag[0] == '^'}
+			stack = append(stack, &frame{section: s})
+		case '/':
+			if top.section == nil || top.section.name != name {
+				return nil, fmt.Errorf("unexpected closing tag %s", name)
+			}
+			top.section.nodes = top.nodes
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			parent.nodes = append(parent.nodes, top.section)
+		default:
+			top.nodes = append(top.nodes, mustacheVar(tag, true))
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unclosed section %s", stack[len(stack)-1].section.name)
+	}
+	top := stack[0]
+	if len(text) > 0 {
+		top.nodes = append(top.nodes, mText(text))
+	}
+	return top.nodes, nil
+}
+
+// mustacheVar returns the node for a variable tag. The yield variable renders
+// the page in layouts, it is never escaped.
+func mustacheVar(name string, escape bool) mNode {
+	if name == yieldName {
+		return mPartial(yieldName)
+	}
+	return &mVar{name: name, escape: escape}
+}
+
+func (m *mustacheTemplates) render(out io.Writer, nodes []mNode, ctx []interface{}, depth int) error {
+	if depth > maxPartialDepth {
+		return fmt.Errorf("utron: mustache partials nested too deep")
+	}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case mText:
+			if _, err := io.WriteString(out, string(n)); err != nil {
+				return err
+			}
+		case *mVar:
+			v, _ := lookupMustache(ctx, n.name)
+			s := ""
+			if v != nil {
+				s = fmt.Sprint(v)
+			}
+			if n.escape {
+				s = html.EscapeString(s)
+			}
+			if _, err := io.WriteString(out, s); err != nil {
+				return err
+			}
+		case mPartial:
+			if partial, ok := m.lookup(string(n)); ok {
+				if err := m.render(out, partial, ctx, depth+1); err != nil {
+					return err
+				}
+			}
+		case *mSection:
+			v, _ := lookupMustache(ctx, n.name)
+			items, truthy := sectionItems(v)
+			if n.inverted {
+				if !truthy {
+					if err := m.render(out, n.nodes, ctx, depth); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !truthy {
+				continue
+			}
+			for _, item := range items {
+				if err := m.render(out, n.nodes, append(ctx, item), depth); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sectionItems returns the values a section is rendered with, and whether the
+// value is truthy.
+func sectionItems(v interface{}) ([]interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return []interface{}{v}, rv.Bool()
+	case reflect.String:
+		return []interface{}{v}, rv.Len() > 0
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+		return items, len(items) > 0
+	case reflect.Ptr, reflect.Interface, reflect.Map:
+		if rv.IsNil() {
+			return nil, false
+		}
+	}
+	return []interface{}{v}, true
+}
+
+// lookupMustache resolves a dotted name against the context stack, starting
+// from the innermost context.
+func lookupMustache(ctx []interface{}, name string) (interface{}, bool) {
+	if name == "." {
+		return ctx[len(ctx)-1], true
+	}
+	parts := strings.Split(name, ".")
+	for i := len(ctx) - 1; i >= 0; i-- {
+		v, ok := resolveMustache(ctx[i], parts[0])
+		if !ok {
+			continue
+		}
+		for _, p := range parts[1:] {
+			if v, ok = resolveMustache(v, p); !ok {
+				return nil, false
+			}
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+// resolveMustache returns the map value, struct field or method result named
+// key of v.
+func resolveMustache(v interface{}, key string) (interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if m := rv.MethodByName(key); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() > 0 {
+		return m.Call(nil)[0].Interface(), true
+	}
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		f := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !f.IsValid() {
+			return nil, false
+		}
+		return f.Interface(), true
+	case reflect.Struct:
+		f := rv.FieldByName(key)
+		if !f.IsValid() || !f.CanInterface() {
+			return nil, false
+		}
+		return f.Interface(), true
+	}
+	return nil, false
+}
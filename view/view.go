@@ -1,13 +1,18 @@
 package view
 
 import (
+	"crypto/sha1"
 	"fmt"
 	"html/template"
 	"io"
-	"io/ioutil"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 // View is an interface for rendering templates.
@@ -15,86 +20,423 @@ type View interface {
 	Render(out io.Writer, name string, data interface{}) error
 }
 
+// LayoutView is a View which can render templates inside layouts.
+type LayoutView interface {
+	View
+
+	// RenderLayout renders template name inside layout. When layout is empty
+	// the layout declared by the template, if any, is used.
+	RenderLayout(out io.Writer, layout, name string, data interface{}) error
+}
+
+// Options are settings for SimpleView.
+type Options struct {
+	// LayoutsDir is the directory, relative to the views directory, holding
+	// layouts. It defaults to layouts.
+	LayoutsDir string
+
+	// PartialsDirs are directories, relative to the views directory, holding
+	// templates shared by all pages. It defaults to partials.
+	PartialsDirs []string
+
+	// Reload enables development mode. The views directory is polled for
+	// changes and templates are parsed again when a file is added, changed or
+	// removed. Parse errors do not stop the view, they are returned by Render
+	// until the templates are fixed.
+	Reload bool
+
+	// ReloadInterval is the time between polls of the views directory. It
+	// defaults to one second.
+	ReloadInterval time.Duration
+
+	// Engines maps file extensions to template engines. It defaults to
+	// DefaultEngines.
+	Engines map[string]Engine
+
+	// FS is the file system the templates are loaded from, the views directory
+	// is a path in it. This allows templates embedded with embed.FS. When nil,
+	// templates are loaded from the disk.
+	FS fs.FS
+
+	// Funcs are template functions added to the DefaultFuncs. Templates are
+	// parsed with these functions, so they must be set for functions used by
+	// the templates.
+	Funcs template.FuncMap
+}
+
+// yieldName is the name of the template rendering the page inside a layout.
+const yieldName = "yield"
+
+var (
+	// yieldTag matches {{yield}} in layouts. The tag is rewritten to execute the
+	// page the layout is rendered for.
+	yieldTag = regexp.MustCompile(`\{\{(-?)\s*yield\s*(-?)\}\}`)
+
+	// layoutTag matches the layout declaration at the start of a page, e.g.
+	//	{{/* layout: application */}}
+	// or for mustache templates
+	//	{{! layout: application }}
+	layoutTag = regexp.MustCompile(`^\s*\{\{-?\s*(?:/\*|!)\s*layout:\s*(\S+?)\s*(?:\*/)?\s*-?\}\}`)
+)
+
 // SimpleView implements View interface, but based on golang templates.
+//
+// Templates found in the layouts and partials directories are shared, every
+// other template is a page. Each page is parsed in its own copy of the shared
+// templates, so pages can define blocks with the same names without colliding.
+//
+// A layout renders the page using {{yield}}. Blocks declared in the layout
+// with {{block "name" .}} can be overridden by the page with {{define "name"}}.
 type SimpleView struct {
 	viewDir string
-	tmpl    *template.Template
+	fsys    fs.FS
+	opts    Options
+
+	mu      sync.RWMutex
+	funcs   template.FuncMap
+	pages   map[string]Templates
+	layouts map[string]string
+	loadErr error
+
+	quit chan struct{}
+	done chan struct{}
 }
 
 //NewSimpleView returns a SimpleView with templates loaded from viewDir
 func NewSimpleView(viewDir string) (View, error) {
-	info, err := os.Stat(viewDir)
+	return NewSimpleViewWithOptions(viewDir, Options{})
+}
+
+// NewSimpleViewWithOptions returns a SimpleView with templates loaded from
+// viewDir using opts.
+func NewSimpleViewWithOptions(viewDir string, opts Options) (View, error) {
+	var fsys fs.FS
+	if opts.FS == nil {
+		fsys = os.DirFS(viewDir)
+	} else {
+		sub, err := fs.Sub(opts.FS, path.Clean(viewDir))
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+	info, err := fs.Stat(fsys, ".")
 	if err != nil {
 		return nil, err
 	}
 	if !info.IsDir() {
 		return nil, fmt.Errorf("utron: %s is not a directory", viewDir)
 	}
+	if opts.LayoutsDir == "" {
+		opts.LayoutsDir = "layouts"
+	}
+	if opts.PartialsDirs == nil {
+		opts.PartialsDirs = []string{"partials"}
+	}
+	if opts.Engines == nil {
+		opts.Engines = DefaultEngines()
+	}
 	s := &SimpleView{
 		viewDir: viewDir,
-		tmpl:    template.New(filepath.Base(viewDir)),
+		fsys:    fsys,
+		opts:    opts,
+		funcs:   DefaultFuncs(),
+	}
+	for k, v := range opts.Funcs {
+		s.funcs[k] = v
+	}
+	if !opts.Reload {
+		return s.load()
+	}
+	if opts.ReloadInterval == 0 {
+		s.opts.ReloadInterval = time.Second
+	}
+	sum, err := s.checksum()
+	if err != nil {
+		return nil, err
 	}
-	return s.load(viewDir)
+	s.reload()
+	s.quit = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.watch(sum)
+	return s, nil
+}
+
+// viewFile is a template file read from the views directory.
+type viewFile struct {
+	name   string
+	ext    string
+	data   string
+	shared bool
 }
 
-// load loads templates from dir. The templates should be valid golang templates
+// load loads templates from the views directory. The templates should be valid templates of the
+// engine registered for their file extension.
 //
-// Only files with extension .html, .tpl, .tmpl will be loaded. references to these templates
-// should be relative to the dir. That is, if  dir is foo, you don't have to refer to
+// Only files with extension .html, .tpl, .tmpl, .txt and .mustache will be loaded by default.
+// references to these templates should be relative to the dir. That is, if  dir is foo, you don't have to refer to
 // foo/bar.tpl, instead just use bar.tpl
-func (s *SimpleView) load(dir string) (View, error) {
+//
+// Templates are also available by their name with the extension, e.g.
+// mail/welcome.txt, which is useful when files differ only by extension.
+func (s *SimpleView) load() (View, error) {
 
-	// supported is the list of file extensions that will be parsed as templates
-	supported := map[string]bool{".tpl": true, ".html": true, ".tmpl": true}
+	// supported maps file extensions that will be parsed as templates to
+	// their engine.
+	supported := s.opts.Engines
 
-	werr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	files := make(map[Engine][]*viewFile)
+	var order []Engine
+	werr := fs.WalkDir(s.fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
+		if d.IsDir() {
 			return nil
 		}
 
 		extension := filepath.Ext(path)
-		if _, ok := supported[extension]; !ok {
+		engine, ok := supported[extension]
+		if !ok {
 			return nil
 		}
 
-		data, err := ioutil.ReadFile(path)
+		data, err := fs.ReadFile(s.fsys, path)
 		if err != nil {
 			return err
 		}
 
-		// We remove the directory name from the path
-		// this means if we have directory foo, with file bar.tpl
-		// full path for bar file foo/bar.tpl
-		// we trim the foo part and remain with /bar.tpl
-		//
-		// NOTE we don't account for the opening slash, when dir ends with /.
-		name := path[len(dir):]
+		// paths are relative to the views directory and slash separated, so
+		// if we have directory foo, with file bar.tpl the path is bar.tpl
+		name := strings.TrimSuffix(path, extension) // remove extension
+
+		if _, ok := files[engine]; !ok {
+			order = append(order, engine)
+		}
+		files[engine] = append(files[engine], &viewFile{
+			name:   name,
+			ext:    extension,
+			data:   string(data),
+			shared: s.isShared(name),
+		})
+		return nil
+	})
+
+	if werr != nil {
+		return nil, werr
+	}
+
+	s.mu.RLock()
+	funcs := s.funcs
+	s.mu.RUnlock()
+
+	pages := make(map[string]Templates)
+	layouts := make(map[string]string)
+	for _, engine := range order {
+		group := files[engine]
+
+		// every page of the same engine gets a clone of the base set, which
+		// has all the templates so pages can include each other with
+		// {{template "other/page"}}. The pages are parsed first, the shared
+		// templates then restore the blocks the pages redefine, and each
+		// clone parses its page again so the blocks of the page win.
+		base := engine.New(filepath.Base(s.viewDir), funcs)
+		for _, shared := range []bool{false, true} {
+			for _, f := range group {
+				if f.shared != shared {
+					continue
+				}
+				if err := base.Parse(f.name, f.data); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for _, f := range group {
+			set, err := base.Clone()
+			if err != nil {
+				return nil, err
+			}
+			if !f.shared {
+				if err = set.Parse(f.name, f.data); err != nil {
+					return nil, err
+				}
+				if err = set.Alias(yieldName, f.name); err != nil {
+					return nil, err
+				}
+				if m := layoutTag.FindStringSubmatch(f.data); m != nil {
+					if _, ok := pages[f.name]; !ok {
+						layouts[f.name] = m[1]
+					}
+					layouts[f.name+f.ext] = m[1]
+				}
+			}
+			if err = set.Alias(f.name+f.ext, f.name); err != nil {
+				return nil, err
+			}
 
-		name = filepath.ToSlash(name)
+			// when files differ only by extension, the name without extension
+			// refers to the first one found.
+			if _, ok := pages[f.name]; !ok {
+				pages[f.name] = set
+			}
+			pages[f.name+f.ext] = set
+		}
+	}
+	s.mu.Lock()
+	s.pages = pages
+	s.layouts = layouts
+	s.loadErr = nil
+	s.mu.Unlock()
+	return s, nil
+}
 
-		name = strings.TrimPrefix(name, "/") // case  we missed the opening slash
+// reload parses the templates again. The parsed templates are swapped only when
+// all of them parse successfully, otherwise the error is kept and returned by
+// Render.
+func (s *SimpleView) reload() {
+	if _, err := s.load(); err != nil {
+		s.mu.Lock()
+		s.loadErr = err
+		s.mu.Unlock()
+	}
+}
 
-		name = strings.TrimSuffix(name, extension) // remove extension
+// watch polls the views directory, and reloads the templates when its checksum
+// differs from sum.
+func (s *SimpleView) watch(sum string) {
+	defer close(s.done)
+	tick := time.NewTicker(s.opts.ReloadInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			current, err := s.checksum()
+			if err != nil {
+				s.mu.Lock()
+				s.loadErr = err
+				s.mu.Unlock()
+				continue
+			}
+			if current != sum {
+				sum = current
+				s.reload()
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
 
-		t := s.tmpl.New(name)
-		
-		if _, err = t.Parse(string(data)); err != nil {
+// checksum returns a string which changes whenever a file in the views
+// directory is added, removed or modified.
+func (s *SimpleView) checksum() (string, error) {
+	h := sha1.New()
+	err := fs.WalkDir(s.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
 			return err
 		}
+		if !d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
 		return nil
 	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
 
-	if werr != nil {
-		return nil, werr
+// Funcs adds funcs to the template functions, and parses the templates again
+// so they can use them. Functions with the same name as existing ones replace
+// them.
+func (s *SimpleView) Funcs(funcs template.FuncMap) error {
+	s.mu.Lock()
+	m := make(template.FuncMap, len(s.funcs)+len(funcs))
+	for k, v := range s.funcs {
+		m[k] = v
+	}
+	for k, v := range funcs {
+		m[k] = v
+	}
+	s.funcs = m
+	s.mu.Unlock()
+	_, err := s.load()
+	if err != nil && s.opts.Reload {
+		s.mu.Lock()
+		s.loadErr = err
+		s.mu.Unlock()
 	}
+	return err
+}
 
-	return s, nil
+// Close stops watching the views directory for changes.
+func (s *SimpleView) Close() error {
+	if s.quit != nil {
+		close(s.quit)
+		<-s.done
+		s.quit = nil
+	}
+	return nil
+}
+
+// isShared returns true if the template name is in the layouts or the partials
+// directories.
+func (s *SimpleView) isShared(name string) bool {
+	dirs := append([]string{s.opts.LayoutsDir}, s.opts.PartialsDirs...)
+	for _, dir := range dirs {
+		if strings.HasPrefix(name, strings.Trim(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 // Render executes template named name, passing data as context, the output is written to out.
+//
+// If the template declares a layout, it is rendered inside the layout.
 func (s *SimpleView) Render(out io.Writer, name string, data interface{}) error {
-	return s.tmpl.ExecuteTemplate(out, name, data)
+	return s.RenderLayout(out, "", name, data)
+}
+
+// Lookup returns true if there is a template named name.
+func (s *SimpleView) Lookup(name string) bool {
+	s.mu.RLock()
+	_, ok := s.pages[name]
+	s.mu.RUnlock()
+	return ok
+}
+
+// RenderLayout executes template named name inside layout, passing data as
+// context. The layout can be named with or without the layouts directory
+// prefix, i.e. application and layouts/application are the same layout.
+func (s *SimpleView) RenderLayout(out io.Writer, layout, name string, data interface{}) error {
+	s.mu.RLock()
+	set, ok := s.pages[name]
+	if layout == "" {
+		layout = s.layouts[name]
+	}
+	loadErr := s.loadErr
+	s.mu.RUnlock()
+	if loadErr != nil {
+		return fmt.Errorf("utron: loading templates %v", loadErr)
+	}
+	if !ok {
+		return fmt.Errorf("utron: no template %q", name)
+	}
+	if layout == "" {
+		return set.Execute(out, name, data)
+	}
+	if !set.Lookup(layout) {
+		prefixed := strings.Trim(s.opts.LayoutsDir, "/") + "/" + layout
+		if !set.Lookup(prefixed) {
+			return fmt.Errorf("utron: no layout %q", layout)
+		}
+		layout = prefixed
+	}
+	return set.Execute(out, layout, data)
 }
@@ -2,8 +2,13 @@ package view
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestSimpleView(t *testing.T) {
@@ -32,6 +37,11 @@ func TestSimpleView(t *testing.T) {
 		}
 	}
 
+	sv := v.(*SimpleView)
+	if !sv.Lookup("sample/hello") || sv.Lookup("sample/bogus") {
+		t.Error("expected only sample/hello to be found")
+	}
+
 	// file instead of a directory
 	_, err = NewSimpleView("fixtures/view/index.tpl")
 	if err == nil {
@@ -45,3 +55,142 @@ func TestSimpleView(t *testing.T) {
 	}
 
 }
+
+func TestSimpleViewLayout(t *testing.T) {
+	v, err := NewSimpleView("../fixtures/layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lv := v.(LayoutView)
+	data := struct {
+		Name string
+	}{
+		"gernest",
+	}
+	sample := []struct {
+		layout, name, expect string
+	}{
+		{"", "pages/one", "<title>one</title>\nheader gernest\nfirst gernest\n"},
+		{"", "pages/two", "second gernest"},
+		{"application", "pages/two", "<title>utron</title>\nheader gernest\nsecond gernest\n"},
+		{"layouts/plain", "pages/one", "plain:first gernest"},
+		{"", "partials/header", "header gernest\n"},
+	}
+	for _, s := range sample {
+		out := &bytes.Buffer{}
+		if err = lv.RenderLayout(out, s.layout, s.name, data); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if out.String() != s.expect {
+			t.Errorf("%s: expected %q got %q", s.name, s.expect, out.String())
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if err = lv.RenderLayout(out, "bogus", "pages/one", data); err == nil {
+		t.Error("expected an error")
+	}
+	if err = v.Render(out, "bogus", data); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSimpleViewReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utron-views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "index.tpl")
+	write := func(s string) {
+		if werr := ioutil.WriteFile(file, []byte(s), 0600); werr != nil {
+			t.Fatal(werr)
+		}
+	}
+
+	// starts even when the templates are broken
+	write("{{.Name")
+	v, err := NewSimpleViewWithOptions(dir, Options{
+		Reload:         true,
+		ReloadInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.(*SimpleView).Close()
+	if err = v.Render(&bytes.Buffer{}, "index", nil); err == nil {
+		t.Error("expected an error")
+	}
+
+	// waitFor renders index until the output is expect.
+	waitFor := func(expect string) {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			out := &bytes.Buffer{}
+			if v.Render(out, "index", nil) == nil && out.String() == expect {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("expected templates to be reloaded with %s", expect)
+	}
+	write("first")
+	waitFor("first")
+	write("second change")
+	waitFor("second change")
+}
+
+func TestSimpleViewFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/index.tpl":        &fstest.MapFile{Data: []byte("hello {{.}}")},
+		"views/sample/hello.tpl": &fstest.MapFile{Data: []byte("sample {{.}}")},
+	}
+	v, err := NewSimpleViewWithOptions("views", Options{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expect := range map[string]string{"index": "hello gernest", "sample/hello": "sample gernest"} {
+		out := &bytes.Buffer{}
+		if err = v.Render(out, name, "gernest"); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != expect {
+			t.Errorf("expected %s got %s", expect, out.String())
+		}
+	}
+	if _, err = NewSimpleViewWithOptions("bogus", Options{FS: fsys}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSimpleViewIncludePage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/layouts/application.tpl": &fstest.MapFile{Data: []byte(`{{block "title" .}}default{{end}}:{{yield}}`)},
+		"views/items/row.tpl":           &fstest.MapFile{Data: []byte(`row {{.}}`)},
+		"views/items/list.tpl":          &fstest.MapFile{Data: []byte(`{{define "title"}}list{{end}}[{{template "items/row" .}}]`)},
+		"views/items/show.tpl":          &fstest.MapFile{Data: []byte(`show {{.}}`)},
+	}
+	v, err := NewSimpleViewWithOptions("views", Options{FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lv := v.(LayoutView)
+	sample := []struct {
+		layout, name, expect string
+	}{
+		// pages can include other pages.
+		{"", "items/list", "[row gernest]"},
+		{"application", "items/list", "list:[row gernest]"},
+		// the blocks of a page do not leak to the other pages.
+		{"application", "items/show", "default:show gernest"},
+	}
+	for _, s := range sample {
+		out := &bytes.Buffer{}
+		if err = lv.RenderLayout(out, s.layout, s.name, "gernest"); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if out.String() != s.expect {
+			t.Errorf("%s %s: expected %q got %q", s.layout, s.name, s.expect, out.String())
+		}
+	}
+}